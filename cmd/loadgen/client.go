@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"time"
+)
+
+type client struct {
+	base    string
+	timeout time.Duration
+	http    *fasthttp.Client
+}
+
+func newClient(base string, concurrency int, timeout time.Duration) *client {
+	return &client{
+		base:    base,
+		timeout: timeout,
+		http:    &fasthttp.Client{MaxConnsPerHost: concurrency},
+	}
+}
+
+// do sends the request and returns the response status; body may be nil.
+func (c *client) do(method, path string, body []byte, out interface{}) (int, error) {
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.Header.SetMethod(method)
+	req.SetRequestURI(c.base + path)
+	if body != nil {
+		req.Header.SetContentType("application/json")
+		req.SetBody(body)
+	}
+	if err := c.http.DoTimeout(req, resp, c.timeout); err != nil {
+		return 0, err
+	}
+	status := resp.StatusCode()
+	if out != nil && status < 300 {
+		if err := json.Unmarshal(resp.Body(), out); err != nil {
+			return status, fmt.Errorf("%s %s: %w", method, path, err)
+		}
+	}
+	return status, nil
+}
+
+// mustCreate posts in and decodes the response, failing on anything but 201.
+func (c *client) mustCreate(path string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	status, err := c.do(fasthttp.MethodPost, path, body, out)
+	if err != nil {
+		return err
+	}
+	if status != fasthttp.StatusCreated && status != fasthttp.StatusOK {
+		return fmt.Errorf("POST %s: unexpected status %d", path, status)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	apiModel "github.com/kzon/technopark-sem2-db/pkg/api/model"
+	"github.com/valyala/fasthttp"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type request struct {
+	method string
+	path   string
+	body   []byte
+}
+
+type route struct {
+	name  string
+	build func(d *dataset, rnd *rand.Rand) request
+}
+
+var routes = []route{
+	{"user_profile", func(d *dataset, rnd *rand.Rand) request {
+		return get("/api/user/" + pick(d.users, rnd) + "/profile")
+	}},
+	{"forum_details", func(d *dataset, rnd *rand.Rand) request {
+		return get("/api/forum/" + pick(d.forums, rnd) + "/details")
+	}},
+	{"forum_threads", func(d *dataset, rnd *rand.Rand) request {
+		return get(fmt.Sprintf("/api/forum/%s/threads?limit=%d&desc=%t", pick(d.forums, rnd), 10+rnd.Intn(90), rnd.Intn(2) == 0))
+	}},
+	{"forum_users", func(d *dataset, rnd *rand.Rand) request {
+		return get(fmt.Sprintf("/api/forum/%s/users?limit=%d&desc=%t", pick(d.forums, rnd), 10+rnd.Intn(90), rnd.Intn(2) == 0))
+	}},
+	{"thread_details", func(d *dataset, rnd *rand.Rand) request {
+		return get("/api/thread/" + pickID(d.threads, rnd) + "/details")
+	}},
+	{"thread_posts_flat", threadPosts("flat")},
+	{"thread_posts_tree", threadPosts("tree")},
+	{"thread_posts_parent_tree", threadPosts("parent_tree")},
+	{"post_details", func(d *dataset, rnd *rand.Rand) request {
+		return get("/api/post/" + pickID(d.posts, rnd) + "/details?related=user,forum,thread")
+	}},
+	{"status", func(d *dataset, rnd *rand.Rand) request {
+		return get("/api/service/status")
+	}},
+	{"post_create", func(d *dataset, rnd *rand.Rand) request {
+		posts := []*apiModel.PostCreate{{Author: pick(d.users, rnd), Message: "load " + strconv.Itoa(rnd.Int())}}
+		return post("/api/thread/"+pickID(d.threads, rnd)+"/create", posts)
+	}},
+	{"post_update", func(d *dataset, rnd *rand.Rand) request {
+		update := apiModel.PostUpdate{Message: "edited " + strconv.Itoa(rnd.Int())}
+		return post("/api/post/"+pickID(d.posts, rnd)+"/details", update)
+	}},
+	{"thread_vote", func(d *dataset, rnd *rand.Rand) request {
+		vote := apiModel.Vote{Nickname: pick(d.users, rnd), Voice: 1 - 2*rnd.Intn(2)}
+		return post("/api/thread/"+pickID(d.threads, rnd)+"/vote", vote)
+	}},
+}
+
+const defaultMix = "user_profile=5,forum_details=10,forum_threads=10,forum_users=5,thread_details=10," +
+	"thread_posts_flat=10,thread_posts_tree=10,thread_posts_parent_tree=10,post_details=15,status=1," +
+	"post_create=8,post_update=3,thread_vote=3"
+
+func threadPosts(sort string) func(d *dataset, rnd *rand.Rand) request {
+	return func(d *dataset, rnd *rand.Rand) request {
+		return get(fmt.Sprintf(
+			"/api/thread/%s/posts?sort=%s&limit=%d&desc=%t", pickID(d.threads, rnd), sort, 10+rnd.Intn(90), rnd.Intn(2) == 0,
+		))
+	}
+}
+
+func get(path string) request {
+	return request{method: fasthttp.MethodGet, path: path}
+}
+
+func post(path string, body interface{}) request {
+	data, _ := json.Marshal(body)
+	return request{method: fasthttp.MethodPost, path: path, body: data}
+}
+
+func pick(values []string, rnd *rand.Rand) string {
+	return values[rnd.Intn(len(values))]
+}
+
+func pickID(values []int, rnd *rand.Rand) string {
+	return strconv.Itoa(values[rnd.Intn(len(values))])
+}
+
+type weightedRoute struct {
+	route
+	cumulative int
+}
+
+// mix picks routes with probabilities proportional to their weights.
+type mix struct {
+	routes []weightedRoute
+	total  int
+}
+
+func parseMix(s string) (*mix, error) {
+	known := make(map[string]route, len(routes))
+	for _, r := range routes {
+		known[r.name] = r
+	}
+	m := &mix{}
+	for _, item := range strings.Split(s, ",") {
+		parts := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("mix item %q is not name=weight", item)
+		}
+		r, ok := known[parts[0]]
+		if !ok {
+			return nil, fmt.Errorf("unknown route %q", parts[0])
+		}
+		weight, err := strconv.Atoi(parts[1])
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("route %s: weight must be a non-negative integer", parts[0])
+		}
+		if weight == 0 {
+			continue
+		}
+		m.total += weight
+		m.routes = append(m.routes, weightedRoute{route: r, cumulative: m.total})
+	}
+	if m.total == 0 {
+		return nil, fmt.Errorf("mix has no routes")
+	}
+	return m, nil
+}
+
+func (m *mix) pick(rnd *rand.Rand) route {
+	n := rnd.Intn(m.total)
+	i := sort.Search(len(m.routes), func(i int) bool { return m.routes[i].cumulative > n })
+	return m.routes[i].route
+}
+
+type sample struct {
+	latency time.Duration
+	status  int
+	failed  bool
+}
+
+// replay sends requests from the mix on concurrency workers until duration passes or limit requests were sent.
+func replay(c *client, d *dataset, m *mix, concurrency int, duration time.Duration, limit int, seed int64) (map[string][]sample, time.Duration) {
+	results := make([]map[string][]sample, concurrency)
+	deadline := time.Now().Add(duration)
+	var sent int64
+	var sentMutex sync.Mutex
+	next := func() bool {
+		if duration > 0 && time.Now().After(deadline) {
+			return false
+		}
+		if limit <= 0 {
+			return true
+		}
+		sentMutex.Lock()
+		defer sentMutex.Unlock()
+		if sent >= int64(limit) {
+			return false
+		}
+		sent++
+		return true
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed + int64(w)))
+			local := make(map[string][]sample)
+			for next() {
+				r := m.pick(rnd)
+				req := r.build(d, rnd)
+				began := time.Now()
+				status, err := c.do(req.method, req.path, req.body, nil)
+				local[r.name] = append(local[r.name], sample{
+					latency: time.Since(began),
+					status:  status,
+					failed:  err != nil || status >= 400,
+				})
+			}
+			results[w] = local
+		}(w)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	merged := make(map[string][]sample)
+	for _, local := range results {
+		for name, samples := range local {
+			merged[name] = append(merged[name], samples...)
+		}
+	}
+	return merged, elapsed
+}
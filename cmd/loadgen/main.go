@@ -0,0 +1,91 @@
+// Command loadgen seeds a forum through the public API and replays a weighted mix of its routes.
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"time"
+)
+
+func main() {
+	target := flag.String("target", "http://localhost:5000", "base URL of the server")
+	concurrency := flag.Int("concurrency", 8, "concurrent requests")
+	duration := flag.Duration("duration", 30*time.Second, "how long to replay the mix, 0 to rely on -requests")
+	requests := flag.Int("requests", 0, "stop after this many requests, 0 for no limit")
+	timeout := flag.Duration("timeout", 5*time.Second, "per-request timeout")
+	mixFlag := flag.String("mix", defaultMix, "comma-separated route=weight pairs")
+	seedValue := flag.Int64("seed", time.Now().UnixNano(), "random seed")
+	jsonPath := flag.String("json", "", "also write the report as JSON to this file, - for stdout")
+
+	cfg := seedConfig{}
+	flag.StringVar(&cfg.prefix, "prefix", "lg"+strconv.FormatInt(time.Now().Unix(), 36), "prefix of seeded nicknames and slugs")
+	flag.IntVar(&cfg.users, "users", 1000, "users to seed")
+	flag.IntVar(&cfg.forums, "forums", 20, "forums to seed")
+	flag.IntVar(&cfg.threads, "threads", 500, "threads to seed")
+	flag.IntVar(&cfg.postsPerThread, "posts", 200, "posts to seed per thread")
+	flag.IntVar(&cfg.depth, "depth", 8, "levels of the seeded post trees")
+	flag.IntVar(&cfg.batch, "batch", 100, "posts per create request while seeding")
+	flag.IntVar(&cfg.votes, "votes", 5000, "votes to seed")
+	flag.Parse()
+
+	if err := validate(cfg, *concurrency, *duration, *requests); err != nil {
+		log.Fatal(err)
+	}
+	m, err := parseMix(*mixFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	c := newClient(*target, *concurrency, *timeout)
+	d, err := seed(c, cfg, *concurrency, rand.New(rand.NewSource(*seedValue)))
+	if err != nil {
+		log.Fatalf("seed: %s", err)
+	}
+
+	log.Printf("replaying the mix at concurrency %d", *concurrency)
+	results, elapsed := replay(c, d, m, *concurrency, *duration, *requests, *seedValue)
+	r := newReport(results, elapsed, *concurrency)
+	if err := r.writeText(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+	if *jsonPath != "" {
+		if err := writeJSONReport(r, *jsonPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func validate(cfg seedConfig, concurrency int, duration time.Duration, requests int) error {
+	switch {
+	case concurrency <= 0:
+		return errors.New("concurrency must be positive")
+	case duration <= 0 && requests <= 0:
+		return errors.New("either duration or requests must be positive")
+	case cfg.users <= 0 || cfg.forums <= 0 || cfg.threads <= 0 || cfg.postsPerThread <= 0:
+		return errors.New("users, forums, threads and posts must be positive")
+	case cfg.depth <= 0 || cfg.batch <= 0:
+		return errors.New("depth and batch must be positive")
+	case cfg.votes < 0:
+		return errors.New("votes must not be negative")
+	}
+	return nil
+}
+
+func writeJSONReport(r report, path string) error {
+	if path == "-" {
+		return r.writeJSON(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := r.writeJSON(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
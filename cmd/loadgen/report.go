@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+type (
+	report struct {
+		Concurrency int           `json:"concurrency"`
+		Duration    float64       `json:"duration_seconds"`
+		Requests    int           `json:"requests"`
+		Errors      int           `json:"errors"`
+		ErrorRate   float64       `json:"error_rate"`
+		Throughput  float64       `json:"rps"`
+		Latency     latency       `json:"latency_ms"`
+		Routes      []routeReport `json:"routes"`
+	}
+
+	routeReport struct {
+		Route      string         `json:"route"`
+		Requests   int            `json:"requests"`
+		Errors     int            `json:"errors"`
+		ErrorRate  float64        `json:"error_rate"`
+		Throughput float64        `json:"rps"`
+		Latency    latency        `json:"latency_ms"`
+		Statuses   map[string]int `json:"statuses"`
+	}
+
+	latency struct {
+		Mean float64 `json:"mean"`
+		P50  float64 `json:"p50"`
+		P90  float64 `json:"p90"`
+		P99  float64 `json:"p99"`
+		Max  float64 `json:"max"`
+	}
+)
+
+func newReport(results map[string][]sample, elapsed time.Duration, concurrency int) report {
+	r := report{Concurrency: concurrency, Duration: elapsed.Seconds()}
+	var all []time.Duration
+	for name, samples := range results {
+		route := routeReport{Route: name, Requests: len(samples), Statuses: make(map[string]int)}
+		latencies := make([]time.Duration, 0, len(samples))
+		for _, s := range samples {
+			latencies = append(latencies, s.latency)
+			if s.failed {
+				route.Errors++
+			}
+			status := "error"
+			if s.status != 0 {
+				status = strconv.Itoa(s.status)
+			}
+			route.Statuses[status]++
+		}
+		route.ErrorRate = ratio(route.Errors, route.Requests)
+		route.Throughput = float64(route.Requests) / elapsed.Seconds()
+		route.Latency = summarize(latencies)
+		r.Routes = append(r.Routes, route)
+		r.Requests += route.Requests
+		r.Errors += route.Errors
+		all = append(all, latencies...)
+	}
+	sort.Slice(r.Routes, func(i, j int) bool { return r.Routes[i].Route < r.Routes[j].Route })
+	r.ErrorRate = ratio(r.Errors, r.Requests)
+	r.Throughput = float64(r.Requests) / elapsed.Seconds()
+	r.Latency = summarize(all)
+	return r
+}
+
+func summarize(latencies []time.Duration) latency {
+	if len(latencies) == 0 {
+		return latency{}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	var sum time.Duration
+	for _, l := range latencies {
+		sum += l
+	}
+	return latency{
+		Mean: milliseconds(sum / time.Duration(len(latencies))),
+		P50:  milliseconds(percentile(latencies, 0.50)),
+		P90:  milliseconds(percentile(latencies, 0.90)),
+		P99:  milliseconds(percentile(latencies, 0.99)),
+		Max:  milliseconds(latencies[len(latencies)-1]),
+	}
+}
+
+// percentile uses the nearest-rank method on sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(p*float64(len(sorted)) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func ratio(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
+
+func (r report) writeText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "route\trequests\trps\terrors\tmean ms\tp50 ms\tp90 ms\tp99 ms\tmax ms\t")
+	for _, route := range r.Routes {
+		writeRow(tw, route.Route, route.Requests, route.Throughput, route.ErrorRate, route.Latency)
+	}
+	writeRow(tw, "total", r.Requests, r.Throughput, r.ErrorRate, r.Latency)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d requests in %.1fs at concurrency %d: %.0f rps, %.2f%% errors\n",
+		r.Requests, r.Duration, r.Concurrency, r.Throughput, r.ErrorRate*100)
+	return err
+}
+
+func writeRow(w io.Writer, name string, requests int, rps, errorRate float64, l latency) {
+	fmt.Fprintf(w, "%s\t%d\t%.1f\t%.2f%%\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t\n",
+		name, requests, rps, errorRate*100, l.Mean, l.P50, l.P90, l.P99, l.Max)
+}
+
+func (r report) writeJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
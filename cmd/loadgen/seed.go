@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	apiModel "github.com/kzon/technopark-sem2-db/pkg/api/model"
+	"github.com/kzon/technopark-sem2-db/pkg/model"
+	"log"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type seedConfig struct {
+	prefix         string
+	users          int
+	forums         int
+	threads        int
+	postsPerThread int
+	depth          int
+	batch          int
+	votes          int
+}
+
+// dataset holds the identifiers the load phase picks its requests from.
+type dataset struct {
+	users   []string
+	forums  []string
+	threads []int
+	posts   []int
+}
+
+func seed(c *client, cfg seedConfig, workers int, rnd *rand.Rand) (*dataset, error) {
+	d := &dataset{}
+	start := time.Now()
+
+	err := parallel(cfg.users, workers, func(i int) error {
+		nickname := fmt.Sprintf("%s_u%d", cfg.prefix, i)
+		user := apiModel.UserInput{
+			Email:    nickname + "@loadgen.example",
+			Fullname: "Load Generator " + strconv.Itoa(i),
+			About:    "seeded by loadgen",
+		}
+		return c.mustCreate("/api/user/"+nickname+"/create", user, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < cfg.users; i++ {
+		d.users = append(d.users, fmt.Sprintf("%s_u%d", cfg.prefix, i))
+	}
+	log.Printf("seeded %d users", len(d.users))
+
+	for i := 0; i < cfg.forums; i++ {
+		forum := apiModel.ForumCreate{
+			Slug:  fmt.Sprintf("%s-f%d", cfg.prefix, i),
+			Title: "Forum " + strconv.Itoa(i),
+			User:  d.users[rnd.Intn(len(d.users))],
+		}
+		if err := c.mustCreate("/api/forum/create", forum, nil); err != nil {
+			return nil, err
+		}
+		d.forums = append(d.forums, forum.Slug)
+	}
+	log.Printf("seeded %d forums", len(d.forums))
+
+	threadAuthors := make([]string, cfg.threads)
+	threadForums := make([]string, cfg.threads)
+	for i := range threadAuthors {
+		threadAuthors[i] = d.users[rnd.Intn(len(d.users))]
+		threadForums[i] = d.forums[rnd.Intn(len(d.forums))]
+	}
+	d.threads = make([]int, cfg.threads)
+	created := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	err = parallel(cfg.threads, workers, func(i int) error {
+		thread := apiModel.ThreadCreate{
+			Author:  threadAuthors[i],
+			Created: created.Add(time.Duration(i) * time.Minute).Format(time.RFC3339),
+			Message: "Thread message " + strconv.Itoa(i),
+			Slug:    fmt.Sprintf("%s-t%d", cfg.prefix, i),
+			Title:   "Thread " + strconv.Itoa(i),
+		}
+		result := model.Thread{}
+		if err := c.mustCreate("/api/forum/"+threadForums[i]+"/create", thread, &result); err != nil {
+			return err
+		}
+		d.threads[i] = result.ID
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("seeded %d threads", len(d.threads))
+
+	var mutex sync.Mutex
+	err = parallel(len(d.threads), workers, func(i int) error {
+		ids, err := seedPostTree(c, cfg, d.threads[i], d.users, rand.New(rand.NewSource(int64(i))))
+		mutex.Lock()
+		d.posts = append(d.posts, ids...)
+		mutex.Unlock()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("seeded %d posts", len(d.posts))
+
+	err = parallel(cfg.votes, workers, func(i int) error {
+		vote := apiModel.Vote{Nickname: d.users[i%len(d.users)], Voice: 1 - 2*(i%2)}
+		thread := d.threads[(i/len(d.users))%len(d.threads)]
+		return c.mustCreate("/api/thread/"+strconv.Itoa(thread)+"/vote", vote, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("seeded %d votes in %s", cfg.votes, time.Since(start).Round(time.Millisecond))
+	return d, nil
+}
+
+// seedPostTree creates the posts of one thread level by level, so trees reach the configured depth.
+func seedPostTree(c *client, cfg seedConfig, thread int, users []string, rnd *rand.Rand) ([]int, error) {
+	var ids, parents []int
+	perLevel := cfg.postsPerThread / cfg.depth
+	if perLevel == 0 {
+		perLevel = 1
+	}
+	for level := 0; level < cfg.depth && len(ids) < cfg.postsPerThread; level++ {
+		count := perLevel
+		if level == cfg.depth-1 || len(ids)+count > cfg.postsPerThread {
+			count = cfg.postsPerThread - len(ids)
+		}
+		var levelIDs []int
+		for created := 0; created < count; created += cfg.batch {
+			size := cfg.batch
+			if created+size > count {
+				size = count - created
+			}
+			posts := make([]*apiModel.PostCreate, 0, size)
+			for i := 0; i < size; i++ {
+				post := &apiModel.PostCreate{
+					Author:  users[rnd.Intn(len(users))],
+					Message: fmt.Sprintf("Post %d at level %d", len(ids)+created+i, level),
+				}
+				if len(parents) > 0 {
+					post.Parent = parents[rnd.Intn(len(parents))]
+				}
+				posts = append(posts, post)
+			}
+			result := model.Posts{}
+			if err := c.mustCreate("/api/thread/"+strconv.Itoa(thread)+"/create", posts, &result); err != nil {
+				return ids, err
+			}
+			for _, p := range result {
+				levelIDs = append(levelIDs, p.ID)
+			}
+		}
+		ids = append(ids, levelIDs...)
+		parents = levelIDs
+	}
+	return ids, nil
+}
+
+// parallel runs f for 0..n-1 on the given number of workers and returns the first error.
+func parallel(n, workers int, f func(i int) error) error {
+	jobs := make(chan int)
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := f(i); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	var err error
+loop:
+	for i := 0; i < n; i++ {
+		select {
+		case jobs <- i:
+		case err = <-errs:
+			break loop
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err == nil {
+		select {
+		case err = <-errs:
+		default:
+		}
+	}
+	return err
+}
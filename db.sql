@@ -1,4 +1,13 @@
 create extension if not exists citext;
+create extension if not exists pg_trgm;
+
+create function set_modified() returns trigger as
+$$
+begin
+    NEW.modified = now();
+    return NEW;
+end;
+$$ language plpgsql;
 
 create table "user"
 (
@@ -8,33 +17,50 @@ create table "user"
     "fullname" text   not null,
     "about"    text   not null default ''
 );
+create index on "user" using gin (("nickname"::text) gin_trgm_ops);
+create index on "user" using gin ("fullname" gin_trgm_ops);
+create index on "user" using gin (("email"::text) gin_trgm_ops);
 
 
 create table "forum"
 (
-    "id"      serial,
-    "slug"    citext        not null primary key,
-    "title"   text          not null,
-    "user"    citext        not null,
-    "posts"   int default 0 not null,
-    "threads" int default 0 not null
+    "id"       serial,
+    "slug"     citext        not null primary key,
+    "title"    text          not null,
+    "user"     citext        not null,
+    "posts"    int default 0 not null,
+    "threads"  int default 0 not null,
+    "modified" timestamptz default now() not null
 );
+create trigger forum_modified
+    before update
+    on forum
+    for each row
+execute procedure set_modified();
+create index on "forum" ("user");
 
 
 create table "thread"
 (
-    "id"      serial primary key,
-    "slug"    citext        not null,
-    "title"   text          not null,
-    "author"  text          not null,
-    "forum"   text          not null,
-    "message" text          not null,
-    "votes"   int default 0 not null,
-    "created" timestamptz   not null
+    "id"       serial primary key,
+    "slug"     citext        not null,
+    "title"    text          not null,
+    "author"   text          not null,
+    "forum"    text          not null,
+    "message"  text          not null,
+    "votes"    int default 0 not null,
+    "created"  timestamptz   not null,
+    "modified" timestamptz   not null default now()
 );
+create trigger thread_modified
+    before update
+    on thread
+    for each row
+execute procedure set_modified();
 create index on "thread" ("slug");
 create index on "thread" ("created", "forum");
 create index on "thread" ("forum", "author");
+create index on "thread" ("author", "created");
 
 create function inc_forum_thread() returns trigger as
 $$
@@ -55,17 +81,63 @@ create table "post"
 (
     "id"       serial primary key,
     "parent"   int         not null,
-    "path"     text        not null default '',
+    "path"     int[]       not null default '{}',
     "author"   text        not null,
     "forum"    text        not null,
     "thread"   int         not null,
     "message"  text        not null,
     "isEdited" bool        not null default false,
-    "created"  timestamptz not null
+    "created"  timestamptz not null,
+    "modified" timestamptz not null default now()
 );
+create trigger post_modified
+    before update
+    on post
+    for each row
+execute procedure set_modified();
+-- post ids are reserved by the application in blocks of the sequence increment
+alter sequence post_id_seq increment by 100;
 create index on "post" ("thread");
-create index on "post" (substring("path",1,7));
+create index on "post" ("thread", "path");
+create index on "post" (("path"[1]), "path");
+create index on "post" ("thread", "id") where "parent" = 0;
 create index on "post" ("forum", "author");
+create index on "post" ("author", "created", "id");
+
+create function inc_forum_posts() returns trigger as
+$$
+begin
+    update forum
+    set posts = posts + inserted.count
+    from (select forum, count(*) as count from new_post group by forum) as inserted
+    where slug = inserted.forum::citext;
+    return null;
+end;
+$$ language plpgsql;
+
+create function dec_forum_posts() returns trigger as
+$$
+begin
+    update forum
+    set posts = posts - deleted.count
+    from (select forum, count(*) as count from old_post group by forum) as deleted
+    where slug = deleted.forum::citext;
+    return null;
+end;
+$$ language plpgsql;
+
+create trigger post_insert
+    after insert
+    on post
+    referencing new table as new_post
+    for each statement
+execute procedure inc_forum_posts();
+create trigger post_delete
+    after delete
+    on post
+    referencing old table as old_post
+    for each statement
+execute procedure dec_forum_posts();
 
 
 create table "vote"
@@ -75,7 +147,8 @@ create table "vote"
     "nickname" text not null,
     "voice"    int  not null
 );
-create index on "vote" ("thread", "nickname");
+create unique index on "vote" ("thread", "nickname");
+create index on "vote" ("nickname");
 
 
 create table "forum_user"
@@ -4,10 +4,11 @@ go 1.13
 
 require (
 	github.com/buaazp/fasthttprouter v0.1.1
+	github.com/jackc/pgconn v1.1.0
 	github.com/jackc/pgx/v4 v4.1.2
-	github.com/jmoiron/sqlx v1.2.0
+	github.com/mailru/easyjson v0.7.7
 	github.com/shopspring/decimal v0.0.0-20191130220710-360f2bc03045 // indirect
+	github.com/valyala/bytebufferpool v1.0.0
 	github.com/valyala/fasthttp v1.7.0
 	golang.org/x/crypto v0.0.0-20191117063200-497ca9f6d64f // indirect
-	google.golang.org/appengine v1.6.5 // indirect
 )
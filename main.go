@@ -1,42 +1,139 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	_ "github.com/jackc/pgx/v4/stdlib"
-	"github.com/jmoiron/sqlx"
+	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/kzon/technopark-sem2-db/pkg/api"
 	"github.com/kzon/technopark-sem2-db/pkg/api/repository"
+	"github.com/kzon/technopark-sem2-db/pkg/config"
+	"github.com/kzon/technopark-sem2-db/pkg/lifecycle"
+	"github.com/kzon/technopark-sem2-db/pkg/metrics"
 	"github.com/valyala/fasthttp"
 	"log"
+	"net"
 	"os"
+	"sync"
 )
 
-const PORT = "5000"
-
 func main() {
-	db, err := NewDB()
+	cfg, err := config.Load(os.Args[1:])
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	repo := repository.NewRepository(db)
+	db, err := NewDB(cfg.DB.DSN, cfg.DB)
+	if err != nil {
+		log.Fatal(err)
+	}
+	replicas := make([]*pgxpool.Pool, 0, len(cfg.DB.ReplicaDSNs))
+	for _, dsn := range cfg.DB.ReplicaDSNs {
+		replica, err := NewDB(dsn, cfg.DB)
+		if err != nil {
+			log.Fatal(err)
+		}
+		replicas = append(replicas, replica)
+	}
+
+	repo := repository.NewRepository(db, replicas, cfg.Repository)
+	repo.StartReplicaMonitor()
 	usecase := api.NewUsecase(repo)
-	handler := api.NewHandler(usecase)
+	handler := api.NewHandler(usecase, cfg)
+
+	server := NewServer(cfg.Server, handler.GetHandleFunc())
+	conns := newIdleConns()
+	server.ConnState = conns.track
 
-	fmt.Println("listening port " + PORT)
-	log.Fatal(fasthttp.ListenAndServe(":"+PORT, handler.GetHandleFunc()))
+	metrics.Register(repo.Collectors()...)
+
+	lc := lifecycle.New(cfg.Server.ShutdownTimeout.Duration)
+	lc.OnStop("database", func(ctx context.Context) error {
+		db.Close()
+		for _, replica := range replicas {
+			replica.Close()
+		}
+		return nil
+	})
+	lc.OnStop("replica monitor", repo.StopReplicaMonitor)
+	lc.OnStop("http server", func(ctx context.Context) error {
+		conns.close()
+		return server.Shutdown()
+	})
+
+	go func() {
+		fmt.Println("listening " + cfg.Server.Listen)
+		if err := server.ListenAndServe(cfg.Server.Listen); err != nil {
+			lc.Fail(err)
+		}
+	}()
+	if err := lc.Run(); err != nil {
+		log.Fatal(err)
+	}
 }
 
-func NewDB() (*sqlx.DB, error) {
-	db, err := sqlx.Open("pgx", os.Getenv("POSTGRES_DSN"))
+func NewDB(dsn string, cfg config.DB) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
 		return nil, err
 	}
-	db.SetMaxOpenConns(8)
-	db.SetMaxIdleConns(8)
-	err = db.Ping()
-	if err != nil {
-		return nil, err
+	poolConfig.MaxConns = int32(cfg.MaxConns)
+	poolConfig.MaxConnLifetime = cfg.MaxConnLifetime.Duration
+	poolConfig.HealthCheckPeriod = cfg.HealthCheckPeriod.Duration
+	poolConfig.AfterConnect = repository.Prepare
+	return pgxpool.ConnectConfig(context.Background(), poolConfig)
+}
+
+func NewServer(cfg config.Server, handler fasthttp.RequestHandler) *fasthttp.Server {
+	return &fasthttp.Server{
+		Handler:            handler,
+		Concurrency:        cfg.Concurrency,
+		ReadTimeout:        cfg.ReadTimeout.Duration,
+		WriteTimeout:       cfg.WriteTimeout.Duration,
+		IdleTimeout:        cfg.IdleTimeout.Duration,
+		MaxRequestBodySize: cfg.MaxRequestBodySize,
+	}
+}
+
+// idleConns tracks the state of every connection so that keep-alive connections waiting for the next request,
+// which Server.Shutdown would otherwise wait on until the idle timeout, can be closed.
+type idleConns struct {
+	mutex   sync.Mutex
+	states  map[net.Conn]fasthttp.ConnState
+	closing bool
+}
+
+func newIdleConns() *idleConns {
+	return &idleConns{states: make(map[net.Conn]fasthttp.ConnState)}
+}
+
+func (i *idleConns) track(c net.Conn, state fasthttp.ConnState) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	switch state {
+	case fasthttp.StateClosed, fasthttp.StateHijacked:
+		delete(i.states, c)
+	case fasthttp.StateIdle:
+		if i.closing {
+			delete(i.states, c)
+			c.Close()
+			return
+		}
+		i.states[c] = state
+	default:
+		i.states[c] = state
+	}
+}
+
+// close closes the idle connections and every connection that becomes idle afterwards. A connection is closed
+// only while its last reported state is idle, under the lock that its transition to active takes.
+func (i *idleConns) close() {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.closing = true
+	for c, state := range i.states {
+		if state == fasthttp.StateIdle {
+			delete(i.states, c)
+			c.Close()
+		}
 	}
-	return db, nil
 }
@@ -0,0 +1,3 @@
+-- post ids are reserved by the application in blocks of the sequence increment
+alter sequence post_id_seq increment by 100;
+select setval('post_id_seq', (select coalesce(max(id), 0) + 1 from post), false);
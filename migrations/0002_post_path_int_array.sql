@@ -0,0 +1,7 @@
+-- old paths are five dot-separated zero-padded ids, trailing levels filled with zeros
+drop index if exists post_substring_idx;
+alter table post alter column path drop default;
+alter table post alter column path type int[] using array_remove(string_to_array(path, '.')::int[], 0);
+alter table post alter column path set default '{}';
+create index on "post" ("thread", "path");
+create index on "post" (("path"[1]), "path");
@@ -0,0 +1 @@
+create index on "post" ("thread", "id") where "parent" = 0;
@@ -0,0 +1,15 @@
+create function set_modified() returns trigger as
+$$
+begin
+    NEW.modified = now();
+    return NEW;
+end;
+$$ language plpgsql;
+
+alter table forum add column "modified" timestamptz not null default now();
+alter table thread add column "modified" timestamptz not null default now();
+alter table post add column "modified" timestamptz not null default now();
+
+create trigger forum_modified before update on forum for each row execute procedure set_modified();
+create trigger thread_modified before update on thread for each row execute procedure set_modified();
+create trigger post_modified before update on post for each row execute procedure set_modified();
@@ -0,0 +1,35 @@
+begin;
+
+create function inc_forum_posts() returns trigger as
+$$
+begin
+    update forum
+    set posts = posts + inserted.count
+    from (select forum, count(*) as count from new_post group by forum) as inserted
+    where slug = inserted.forum::citext;
+    return null;
+end;
+$$ language plpgsql;
+
+create function dec_forum_posts() returns trigger as
+$$
+begin
+    update forum
+    set posts = posts - deleted.count
+    from (select forum, count(*) as count from old_post group by forum) as deleted
+    where slug = deleted.forum::citext;
+    return null;
+end;
+$$ language plpgsql;
+
+lock table forum, post in share row exclusive mode;
+
+create trigger post_insert after insert on post referencing new table as new_post
+    for each statement execute procedure inc_forum_posts();
+create trigger post_delete after delete on post referencing old table as old_post
+    for each statement execute procedure dec_forum_posts();
+
+-- counters written by the old lazy recount may be stale
+update forum set posts = (select count(*) from post where post.forum = forum.slug::text);
+
+commit;
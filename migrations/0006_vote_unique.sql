@@ -0,0 +1,27 @@
+begin;
+
+lock table vote, thread in share row exclusive mode;
+
+-- keep the latest voice of every user per thread
+delete from vote
+where id in (
+    select id
+    from (select id, row_number() over (partition by thread, nickname order by id desc) as n from vote) as ranked
+    where n > 1
+);
+
+update thread
+set votes = totals.votes
+from (
+    select thread.id, coalesce(sum(vote.voice), 0) as votes
+    from thread
+             left join vote on vote.thread = thread.id
+    group by thread.id
+) as totals
+where thread.id = totals.id
+  and thread.votes <> totals.votes;
+
+drop index if exists vote_thread_nickname_idx;
+create unique index vote_thread_nickname_idx on vote (thread, nickname);
+
+commit;
@@ -0,0 +1,5 @@
+create extension if not exists pg_trgm;
+
+create index if not exists user_nickname_trgm_idx on "user" using gin ((nickname::text) gin_trgm_ops);
+create index if not exists user_fullname_trgm_idx on "user" using gin (fullname gin_trgm_ops);
+create index if not exists user_email_trgm_idx on "user" using gin ((email::text) gin_trgm_ops);
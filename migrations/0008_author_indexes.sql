@@ -0,0 +1,5 @@
+-- nickname renames update every copy of the nickname by value
+create index if not exists forum_owner_idx on forum ("user");
+create index if not exists thread_author_idx on thread (author);
+create index if not exists post_author_idx on post (author);
+create index if not exists vote_nickname_idx on vote (nickname);
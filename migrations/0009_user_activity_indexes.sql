@@ -0,0 +1,6 @@
+-- per-user listings page by creation time; these also serve nickname renames in place of the single-column indexes
+create index if not exists thread_author_created_idx on thread (author, created);
+create index if not exists post_author_created_idx on post (author, created, id);
+
+drop index if exists thread_author_idx;
+drop index if exists post_author_idx;
@@ -0,0 +1,27 @@
+-- authors used to be stored as the client spelled them; nickname renames and deletions match the registered spelling exactly
+begin;
+
+update thread set author = u.nickname
+from "user" u
+where thread.author::citext = u.nickname and thread.author <> u.nickname::text;
+
+update post set author = u.nickname
+from "user" u
+where post.author::citext = u.nickname and post.author <> u.nickname::text;
+
+update forum set "user" = u.nickname
+from "user" u
+where forum."user" = u.nickname and forum."user"::text <> u.nickname::text;
+
+insert into forum_user (forum, "user")
+select fu.forum, u.nickname
+from forum_user fu
+         join "user" u on fu."user"::citext = u.nickname
+where fu."user" <> u.nickname::text
+on conflict do nothing;
+
+delete from forum_user fu
+    using "user" u
+where fu."user"::citext = u.nickname and fu."user" <> u.nickname::text;
+
+commit;
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"bufio"
+	"github.com/kzon/technopark-sem2-db/pkg/api/repository"
+	"github.com/kzon/technopark-sem2-db/pkg/model"
+	"github.com/mailru/easyjson"
+)
+
+// writeUserExport streams the export as one JSON object, encoding a row at a time.
+func writeUserExport(w *bufio.Writer, export *repository.UserExport) error {
+	w.WriteString(`{"profile":`)
+	if _, err := easyjson.MarshalToWriter(export.User, w); err != nil {
+		return err
+	}
+	sections := []struct {
+		name  string
+		write func(a *exportArray) error
+	}{
+		{"forums", func(a *exportArray) error {
+			return export.Forums(func(f *model.Forum) error { return a.write(f) })
+		}},
+		{"threads", func(a *exportArray) error {
+			return export.Threads(func(t *model.Thread) error { return a.write(t) })
+		}},
+		{"posts", func(a *exportArray) error {
+			return export.Posts(func(p *model.Post) error { return a.write(p) })
+		}},
+		{"votes", func(a *exportArray) error {
+			return export.Votes(func(v *model.Vote) error { return a.write(v) })
+		}},
+	}
+	for _, section := range sections {
+		w.WriteString(`,"` + section.name + `":[`)
+		if err := section.write(&exportArray{w: w, first: true}); err != nil {
+			return err
+		}
+		w.WriteByte(']')
+	}
+	w.WriteByte('}')
+	return w.Flush()
+}
+
+type exportArray struct {
+	w     *bufio.Writer
+	first bool
+}
+
+func (a *exportArray) write(item easyjson.Marshaler) error {
+	if !a.first {
+		a.w.WriteByte(',')
+	}
+	a.first = false
+	_, err := easyjson.MarshalToWriter(item, a.w)
+	return err
+}
@@ -1,56 +1,94 @@
 package api
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"github.com/buaazp/fasthttprouter"
 	apiModel "github.com/kzon/technopark-sem2-db/pkg/api/model"
+	"github.com/kzon/technopark-sem2-db/pkg/config"
 	"github.com/kzon/technopark-sem2-db/pkg/consts"
 	"github.com/kzon/technopark-sem2-db/pkg/deliv"
+	"github.com/kzon/technopark-sem2-db/pkg/metrics"
+	"github.com/mailru/easyjson"
 	"github.com/valyala/fasthttp"
+	"log"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Handler struct {
-	usecase *Usecase
-	router  *fasthttprouter.Router
+	usecase     *Usecase
+	router      *fasthttprouter.Router
+	config      *config.Config
+	forumCreate fasthttp.RequestHandler
 }
 
-func NewHandler(usecase Usecase) Handler {
+func NewHandler(usecase Usecase, cfg *config.Config) Handler {
 	h := Handler{
 		usecase: &usecase,
 		router:  fasthttprouter.New(),
+		config:  cfg,
 	}
 
-	h.router.POST("/api/user/:nickname/create", h.handleUserCreate)
-	h.router.GET("/api/user/:nickname/profile", h.handleGetUserProfile)
-	h.router.POST("/api/user/:nickname/profile", h.handleUserUpdate)
+	h.post("/api/user/:nickname/create", h.handleUserCreate)
+	h.get("/api/user/:nickname/profile", h.handleGetUserProfile)
+	h.post("/api/user/:nickname/profile", h.handleUserUpdate)
+	h.delete("/api/user/:nickname/profile", h.handleUserDelete)
+	h.get("/api/user/:nickname/export", h.handleUserExport)
+	h.get("/api/user/:nickname/posts", h.handleGetUserPosts)
+	h.get("/api/user/:nickname/threads", h.handleGetUserThreads)
+	h.get("/api/users", h.handleSearchUsers)
 
-	h.router.POST("/api/forum/:slug/create", h.handleThreadCreate)
-	h.router.GET("/api/forum/:slug/details", h.handleGetForumDetails)
-	h.router.GET("/api/forum/:slug/threads", h.handleGetForumThreads)
-	h.router.GET("/api/forum/:slug/users", h.handleGetForumUsers)
+	h.post("/api/forum/:slug/create", h.handleThreadCreate)
+	h.get("/api/forum/:slug/details", h.handleGetForumDetails)
+	h.get("/api/forum/:slug/threads", h.handleGetForumThreads)
+	h.get("/api/forum/:slug/users", h.handleGetForumUsers)
 
-	h.router.POST("/api/thread/:slug_or_id/create", h.handlePostCreate)
-	h.router.POST("/api/thread/:slug_or_id/vote", h.handleVoteForThread)
-	h.router.GET("/api/thread/:slug_or_id/details", h.handleGetThreadDetails)
-	h.router.POST("/api/thread/:slug_or_id/details", h.handleThreadUpdate)
-	h.router.GET("/api/thread/:slug_or_id/posts", h.handleGetThreadPosts)
+	h.post("/api/thread/:slug_or_id/create", h.handlePostCreate)
+	h.post("/api/thread/:slug_or_id/vote", h.handleVoteForThread)
+	h.get("/api/thread/:slug_or_id/details", h.handleGetThreadDetails)
+	h.post("/api/thread/:slug_or_id/details", h.handleThreadUpdate)
+	h.get("/api/thread/:slug_or_id/posts", h.handleGetThreadPosts)
 
-	h.router.GET("/api/post/:id/details", h.handleGetPostDetails)
-	h.router.POST("/api/post/:id/details", h.handlePostUpdate)
+	h.get("/api/post/:id/details", h.handleGetPostDetails)
+	h.post("/api/post/:id/details", h.handlePostUpdate)
 
-	h.router.GET("/api/service/status", h.handleStatus)
-	h.router.POST("/api/service/clear", h.handleClear)
+	h.get("/api/service/status", h.handleStatus)
+	h.get("/api/service/pool", h.handlePoolStat)
+	h.get("/api/service/config", h.handleConfig)
+	h.post("/api/service/clear", h.handleClear)
+	h.post("/api/service/ingest", h.handleIngest)
+	h.get("/api/service/cache", h.handleCacheStats)
+	h.post("/api/service/cache/flush", h.handleCacheFlush)
+
+	h.get("/metrics", h.handleMetrics)
+	h.router.NotFound = instrument(routeNotFound, func(c *fasthttp.RequestCtx) {
+		c.Error(fasthttp.StatusMessage(fasthttp.StatusNotFound), fasthttp.StatusNotFound)
+	})
+	h.forumCreate = instrument("/api/forum/create", h.handleForumCreate)
 
 	return h
 }
 
+func (h *Handler) get(path string, handler fasthttp.RequestHandler) {
+	h.router.GET(path, instrument(path, handler))
+}
+
+func (h *Handler) post(path string, handler fasthttp.RequestHandler) {
+	h.router.POST(path, instrument(path, handler))
+}
+
+func (h *Handler) delete(path string, handler fasthttp.RequestHandler) {
+	h.router.DELETE(path, instrument(path, handler))
+}
+
 func (h *Handler) GetHandleFunc() fasthttp.RequestHandler {
 	return func(c *fasthttp.RequestCtx) {
 		if string(c.Path()) == "/api/forum/create" {
-			h.handleForumCreate(c)
+			h.forumCreate(c)
 		} else {
 			h.router.Handler(c)
 		}
@@ -59,7 +97,7 @@ func (h *Handler) GetHandleFunc() fasthttp.RequestHandler {
 
 func (h *Handler) handleUserCreate(c *fasthttp.RequestCtx) {
 	u := apiModel.UserInput{}
-	if err := json.Unmarshal(c.PostBody(), &u); err != nil {
+	if err := easyjson.Unmarshal(c.PostBody(), &u); err != nil {
 		deliv.BadRequest(c, err)
 		return
 	}
@@ -86,12 +124,12 @@ func (h *Handler) handleGetUserProfile(c *fasthttp.RequestCtx) {
 
 func (h *Handler) handleUserUpdate(c *fasthttp.RequestCtx) {
 	u := apiModel.UserInput{}
-	if err := json.Unmarshal(c.PostBody(), &u); err != nil {
+	if err := easyjson.Unmarshal(c.PostBody(), &u); err != nil {
 		deliv.BadRequest(c, err)
 		return
 	}
 	nick := deliv.PathParam(c, "nickname")
-	user, err := h.usecase.updateUser(nick, u.Email, u.Fullname, u.About)
+	user, err := h.usecase.updateUser(nick, u)
 	if errors.Is(err, consts.ErrConflict) {
 		deliv.ConflictWithMessage(c, err)
 		return
@@ -103,9 +141,93 @@ func (h *Handler) handleUserUpdate(c *fasthttp.RequestCtx) {
 	deliv.Ok(c, user)
 }
 
+func (h *Handler) handleUserDelete(c *fasthttp.RequestCtx) {
+	if err := h.usecase.deleteUser(deliv.PathParam(c, "nickname")); err != nil {
+		deliv.Error(c, err)
+		return
+	}
+	deliv.NoContent(c)
+}
+
+func (h *Handler) handleUserExport(c *fasthttp.RequestCtx) {
+	export, err := h.usecase.exportUser(deliv.PathParam(c, "nickname"))
+	if err != nil {
+		deliv.Error(c, err)
+		return
+	}
+	c.SetContentType("application/json")
+	c.Response.Header.Set("Content-Disposition", "attachment; filename="+strconv.Quote(export.User.Nickname+".json"))
+	c.SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer export.Close()
+		if err := writeUserExport(w, export); err != nil {
+			log.Printf("export %s: %s", export.User.Nickname, err)
+		}
+	})
+}
+
+func (h *Handler) handleGetUserPosts(c *fasthttp.RequestCtx) {
+	var since *int
+	if sp := deliv.QueryParam(c, "since"); sp != "" {
+		n, _ := strconv.Atoi(sp)
+		since = &n
+	}
+	limit, _ := strconv.Atoi(deliv.QueryParam(c, "limit"))
+	desc, _ := strconv.ParseBool(deliv.QueryParam(c, "desc"))
+	posts, err := h.usecase.getUserPosts(
+		deliv.PathParam(c, "nickname"),
+		deliv.QueryParam(c, "forum"),
+		deliv.QueryParam(c, "from"),
+		deliv.QueryParam(c, "to"),
+		since,
+		limit,
+		desc,
+	)
+	if err != nil {
+		deliv.Error(c, err)
+		return
+	}
+	deliv.Ok(c, posts)
+}
+
+func (h *Handler) handleGetUserThreads(c *fasthttp.RequestCtx) {
+	limit, _ := strconv.Atoi(deliv.QueryParam(c, "limit"))
+	desc, _ := strconv.ParseBool(deliv.QueryParam(c, "desc"))
+	threads, err := h.usecase.getUserThreads(
+		deliv.PathParam(c, "nickname"),
+		deliv.QueryParam(c, "forum"),
+		deliv.QueryParam(c, "from"),
+		deliv.QueryParam(c, "to"),
+		deliv.QueryParam(c, "since"),
+		limit,
+		desc,
+	)
+	if err != nil {
+		deliv.Error(c, err)
+		return
+	}
+	deliv.Ok(c, threads)
+}
+
+func (h *Handler) handleSearchUsers(c *fasthttp.RequestCtx) {
+	limit, _ := strconv.Atoi(deliv.QueryParam(c, "limit"))
+	desc, _ := strconv.ParseBool(deliv.QueryParam(c, "desc"))
+	users, err := h.usecase.searchUsers(
+		deliv.QueryParam(c, "query"),
+		deliv.QueryParam(c, "match"),
+		deliv.QueryParam(c, "since"),
+		limit,
+		desc,
+	)
+	if err != nil {
+		deliv.Error(c, err)
+		return
+	}
+	deliv.Ok(c, users)
+}
+
 func (h *Handler) handleForumCreate(c *fasthttp.RequestCtx) {
 	forumToCreate := apiModel.ForumCreate{}
-	if err := json.Unmarshal(c.PostBody(), &forumToCreate); err != nil {
+	if err := easyjson.Unmarshal(c.PostBody(), &forumToCreate); err != nil {
 		deliv.BadRequest(c, err)
 		return
 	}
@@ -123,7 +245,7 @@ func (h *Handler) handleForumCreate(c *fasthttp.RequestCtx) {
 
 func (h *Handler) handleThreadCreate(c *fasthttp.RequestCtx) {
 	thread := apiModel.ThreadCreate{}
-	if err := json.Unmarshal(c.PostBody(), &thread); err != nil {
+	if err := easyjson.Unmarshal(c.PostBody(), &thread); err != nil {
 		deliv.BadRequest(c, err)
 		return
 	}
@@ -147,7 +269,7 @@ func (h *Handler) handleGetForumDetails(c *fasthttp.RequestCtx) {
 		deliv.Error(c, err)
 		return
 	}
-	deliv.Ok(c, forum)
+	deliv.OkConditional(c, forum, forum.Modified)
 }
 
 func (h *Handler) handleGetForumThreads(c *fasthttp.RequestCtx) {
@@ -173,8 +295,8 @@ func (h *Handler) handleGetForumUsers(c *fasthttp.RequestCtx) {
 }
 
 func (h *Handler) handlePostCreate(c *fasthttp.RequestCtx) {
-	var posts []*apiModel.PostCreate
-	if err := json.Unmarshal(c.PostBody(), &posts); err != nil {
+	var posts apiModel.PostsCreate
+	if err := easyjson.Unmarshal(c.PostBody(), &posts); err != nil {
 		deliv.BadRequest(c, err)
 		return
 	}
@@ -188,7 +310,7 @@ func (h *Handler) handlePostCreate(c *fasthttp.RequestCtx) {
 
 func (h *Handler) handleVoteForThread(c *fasthttp.RequestCtx) {
 	var vote apiModel.Vote
-	if err := json.Unmarshal(c.PostBody(), &vote); err != nil {
+	if err := easyjson.Unmarshal(c.PostBody(), &vote); err != nil {
 		deliv.BadRequest(c, err)
 		return
 	}
@@ -206,12 +328,12 @@ func (h *Handler) handleGetThreadDetails(c *fasthttp.RequestCtx) {
 		deliv.Error(c, err)
 		return
 	}
-	deliv.Ok(c, thread)
+	deliv.OkConditional(c, thread, thread.Modified)
 }
 
 func (h *Handler) handleThreadUpdate(c *fasthttp.RequestCtx) {
 	t := apiModel.ThreadUpdate{}
-	if err := json.Unmarshal(c.PostBody(), &t); err != nil {
+	if err := easyjson.Unmarshal(c.PostBody(), &t); err != nil {
 		deliv.BadRequest(c, err)
 		return
 	}
@@ -243,7 +365,7 @@ func (h *Handler) handleGetThreadPosts(c *fasthttp.RequestCtx) {
 		deliv.Error(c, err)
 		return
 	}
-	deliv.Ok(c, posts)
+	deliv.OkConditionalArray(c, posts, time.Time{})
 }
 
 func (h *Handler) handleGetPostDetails(c *fasthttp.RequestCtx) {
@@ -254,25 +376,30 @@ func (h *Handler) handleGetPostDetails(c *fasthttp.RequestCtx) {
 		deliv.Error(c, err)
 		return
 	}
-	result := map[string]interface{}{
-		"post": details.Post,
+	lastModified := details.Post.Modified
+	if details.Forum != nil {
+		lastModified = latest(lastModified, details.Forum.Modified)
 	}
-	for _, r := range related {
-		switch r {
-		case "user":
-			result["author"] = details.Author
-		case "forum":
-			result["forum"] = details.Forum
-		case "thread":
-			result["thread"] = details.Thread
-		}
+	if details.Thread != nil {
+		lastModified = latest(lastModified, details.Thread.Modified)
+	}
+	if details.Author != nil {
+		// users carry no modification time, so only the ETag can tell their changes
+		lastModified = time.Time{}
 	}
-	deliv.Ok(c, result)
+	deliv.OkConditional(c, details, lastModified)
+}
+
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
 }
 
 func (h *Handler) handlePostUpdate(c *fasthttp.RequestCtx) {
 	t := apiModel.PostUpdate{}
-	if err := json.Unmarshal(c.PostBody(), &t); err != nil {
+	if err := easyjson.Unmarshal(c.PostBody(), &t); err != nil {
 		deliv.BadRequest(c, err)
 		return
 	}
@@ -294,6 +421,21 @@ func (h *Handler) handleStatus(c *fasthttp.RequestCtx) {
 	deliv.Ok(c, status)
 }
 
+func (h *Handler) handlePoolStat(c *fasthttp.RequestCtx) {
+	deliv.Ok(c, h.usecase.getPoolStat())
+}
+
+func (h *Handler) handleConfig(c *fasthttp.RequestCtx) {
+	deliv.Ok(c, h.config.Redacted())
+}
+
+func (h *Handler) handleMetrics(c *fasthttp.RequestCtx) {
+	c.SetContentType("text/plain; version=0.0.4")
+	if err := metrics.Default.Write(c); err != nil {
+		deliv.Error(c, err)
+	}
+}
+
 func (h *Handler) handleClear(c *fasthttp.RequestCtx) {
 	err := h.usecase.clear()
 	if err != nil {
@@ -303,3 +445,34 @@ func (h *Handler) handleClear(c *fasthttp.RequestCtx) {
 	deliv.Ok(c, nil)
 	return
 }
+
+func (h *Handler) handleCacheStats(c *fasthttp.RequestCtx) {
+	deliv.Ok(c, h.usecase.getCacheStats())
+}
+
+func (h *Handler) handleCacheFlush(c *fasthttp.RequestCtx) {
+	if err := h.usecase.flushCache(deliv.QueryParam(c, "name")); err != nil {
+		deliv.Error(c, err)
+		return
+	}
+	deliv.Ok(c, h.usecase.getCacheStats())
+}
+
+func (h *Handler) handleIngest(c *fasthttp.RequestCtx) {
+	records := make([]*apiModel.IngestRecord, 0)
+	decoder := json.NewDecoder(bytes.NewReader(c.PostBody()))
+	for decoder.More() {
+		record := &apiModel.IngestRecord{}
+		if err := decoder.Decode(record); err != nil {
+			deliv.BadRequest(c, err)
+			return
+		}
+		records = append(records, record)
+	}
+	result, err := h.usecase.ingest(records)
+	if err != nil {
+		deliv.Error(c, err)
+		return
+	}
+	deliv.Created(c, result)
+}
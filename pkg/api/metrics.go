@@ -0,0 +1,33 @@
+package api
+
+import (
+	"github.com/kzon/technopark-sem2-db/pkg/metrics"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"time"
+)
+
+const routeNotFound = "not_found"
+
+var (
+	requestsTotal = metrics.NewCounterVec(
+		"http_requests_total", "HTTP requests by route and response code.", "method", "route", "code",
+	)
+	requestDuration = metrics.NewHistogramVec(
+		"http_request_duration_seconds", "HTTP request latency by route.", metrics.DefaultBuckets, "method", "route",
+	)
+)
+
+func init() {
+	metrics.Register(requestsTotal, requestDuration)
+}
+
+func instrument(route string, handler fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(c *fasthttp.RequestCtx) {
+		start := time.Now()
+		handler(c)
+		method := string(c.Method())
+		requestDuration.Observe(time.Since(start).Seconds(), method, route)
+		requestsTotal.Inc(method, route, strconv.Itoa(c.Response.StatusCode()))
+	}
+}
@@ -1,7 +1,13 @@
+//go:generate easyjson model.go
+
 package model
 
+import "github.com/kzon/technopark-sem2-db/pkg/model"
+
+//easyjson:json
 type (
 	UserInput struct {
+		Nickname string `json:"nickname"`
 		Email    string `json:"email"`
 		Fullname string `json:"fullname"`
 		About    string `json:"about"`
@@ -32,6 +38,8 @@ type (
 		Parent  int    `json:"parent"`
 	}
 
+	PostsCreate []*PostCreate
+
 	PostUpdate struct {
 		Message string `json:"message"`
 	}
@@ -47,4 +55,38 @@ type (
 		Thread int `json:"thread"`
 		User   int `json:"user"`
 	}
+
+	PostDetails struct {
+		Author *model.User   `json:"author,omitempty"`
+		Forum  *model.Forum  `json:"forum,omitempty"`
+		Post   *model.Post   `json:"post"`
+		Thread *model.Thread `json:"thread,omitempty"`
+	}
+
+	IngestRecord struct {
+		User   *model.User   `json:"user"`
+		Forum  *ForumCreate  `json:"forum"`
+		Thread *model.Thread `json:"thread"`
+		Post   *model.Post   `json:"post"`
+	}
+
+	IngestResult struct {
+		Users   int `json:"users"`
+		Forums  int `json:"forums"`
+		Threads int `json:"threads"`
+		Posts   int `json:"posts"`
+	}
 )
+
+// PoolStat is left to encoding/json: generated code formats floats differently.
+type PoolStat struct {
+	AcquireCount         int64   `json:"acquire_count"`
+	AcquireDuration      float64 `json:"acquire_duration_seconds"`
+	AcquiredConns        int32   `json:"acquired_conns"`
+	CanceledAcquireCount int64   `json:"canceled_acquire_count"`
+	ConstructingConns    int32   `json:"constructing_conns"`
+	EmptyAcquireCount    int64   `json:"empty_acquire_count"`
+	IdleConns            int32   `json:"idle_conns"`
+	MaxConns             int32   `json:"max_conns"`
+	TotalConns           int32   `json:"total_conns"`
+}
@@ -0,0 +1,30 @@
+package cache
+
+import "sync"
+
+// generation counts invalidations, so a value read from the database before one is not cached after it.
+type generation struct {
+	mutex sync.RWMutex
+	value uint64
+}
+
+func (g *generation) current() uint64 {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return g.value
+}
+
+func (g *generation) addSince(value uint64, add func()) {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	if g.value == value {
+		add()
+	}
+}
+
+func (g *generation) invalidate(invalidate func()) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.value++
+	invalidate()
+}
@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"github.com/kzon/technopark-sem2-db/pkg/consts"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ForumCache maps a forum slug in any case to its canonical slug.
+// Like UserCache, it only takes lookups that no invalidation raced with.
+type ForumCache struct {
+	*LRU
+	generation generation
+}
+
+func NewForumCache(size int, ttl time.Duration) *ForumCache {
+	return &ForumCache{LRU: NewLRU(size, ttl)}
+}
+
+func (f *ForumCache) GetSlug(slug string) (string, error) {
+	canonical, ok := f.Get(strings.ToLower(slug))
+	if !ok {
+		return "", consts.ErrNotFound
+	}
+	return canonical.(string), nil
+}
+
+func (f *ForumCache) Add(slug string) {
+	f.Set(strings.ToLower(slug), slug)
+}
+
+func (f *ForumCache) Generation() uint64 {
+	return f.generation.current()
+}
+
+func (f *ForumCache) AddSince(generation uint64, slug string) {
+	f.generation.addSince(generation, func() { f.Add(slug) })
+}
+
+func (f *ForumCache) Invalidate(slug string) {
+	f.generation.invalidate(func() { f.Delete(strings.ToLower(slug)) })
+}
+
+func (f *ForumCache) Clear() {
+	f.generation.invalidate(f.LRU.Clear)
+}
+
+type ThreadIdentity struct {
+	ID    int
+	Slug  string
+	Forum string
+}
+
+// ThreadCache resolves a thread slug or id to the thread id and forum.
+type ThreadCache struct {
+	*LRU
+	generation generation
+}
+
+func NewThreadCache(size int, ttl time.Duration) *ThreadCache {
+	return &ThreadCache{LRU: NewLRU(size, ttl)}
+}
+
+func (t *ThreadCache) GetBySlugOrID(slugOrID string) (ThreadIdentity, error) {
+	key := t.slugKey(slugOrID)
+	if id, err := strconv.Atoi(slugOrID); err == nil {
+		key = t.idKey(id)
+	}
+	thread, ok := t.Get(key)
+	if !ok {
+		return ThreadIdentity{}, consts.ErrNotFound
+	}
+	return thread.(ThreadIdentity), nil
+}
+
+func (t *ThreadCache) Add(thread ThreadIdentity) {
+	t.Set(t.idKey(thread.ID), thread)
+	if thread.Slug != "" {
+		t.Set(t.slugKey(thread.Slug), thread)
+	}
+}
+
+func (t *ThreadCache) Generation() uint64 {
+	return t.generation.current()
+}
+
+func (t *ThreadCache) AddSince(generation uint64, thread ThreadIdentity) {
+	t.generation.addSince(generation, func() { t.Add(thread) })
+}
+
+func (t *ThreadCache) Invalidate(thread ThreadIdentity) {
+	t.generation.invalidate(func() {
+		t.Delete(t.idKey(thread.ID))
+		if thread.Slug != "" {
+			t.Delete(t.slugKey(thread.Slug))
+		}
+	})
+}
+
+func (t *ThreadCache) Clear() {
+	t.generation.invalidate(t.LRU.Clear)
+}
+
+func (t *ThreadCache) idKey(id int) string {
+	return "id:" + strconv.Itoa(id)
+}
+
+func (t *ThreadCache) slugKey(slug string) string {
+	return "slug:" + strings.ToLower(slug)
+}
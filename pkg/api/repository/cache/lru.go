@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"container/list"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const shardCount = 16
+
+type entry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+type shard struct {
+	mutex    sync.Mutex
+	items    map[string]*list.Element
+	order    *list.List
+	capacity int
+}
+
+// LRU is a size-bounded cache with optional TTL, split into shards so lookups of different keys do not contend.
+type LRU struct {
+	shards []*shard
+	ttl    time.Duration
+
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+func NewLRU(size int, ttl time.Duration) *LRU {
+	capacity := (size + shardCount - 1) / shardCount
+	if capacity < 1 {
+		capacity = 1
+	}
+	c := &LRU{shards: make([]*shard, shardCount), ttl: ttl}
+	for i := range c.shards {
+		c.shards[i] = &shard{
+			items:    make(map[string]*list.Element),
+			order:    list.New(),
+			capacity: capacity,
+		}
+	}
+	return c
+}
+
+func (c *LRU) Get(key string) (interface{}, bool) {
+	s := c.shard(key)
+	s.mutex.Lock()
+	element, ok := s.items[key]
+	if ok && c.expired(element.Value.(*entry)) {
+		s.remove(element)
+		ok = false
+	}
+	var value interface{}
+	if ok {
+		s.order.MoveToFront(element)
+		value = element.Value.(*entry).value
+	}
+	s.mutex.Unlock()
+	if ok {
+		atomic.AddUint64(&c.hits, 1)
+	} else {
+		atomic.AddUint64(&c.misses, 1)
+	}
+	return value, ok
+}
+
+func (c *LRU) Set(key string, value interface{}) {
+	var expires time.Time
+	if c.ttl > 0 {
+		expires = time.Now().Add(c.ttl)
+	}
+	s := c.shard(key)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if element, ok := s.items[key]; ok {
+		element.Value = &entry{key: key, value: value, expires: expires}
+		s.order.MoveToFront(element)
+		return
+	}
+	s.items[key] = s.order.PushFront(&entry{key: key, value: value, expires: expires})
+	for s.order.Len() > s.capacity {
+		s.remove(s.order.Back())
+		atomic.AddUint64(&c.evictions, 1)
+	}
+}
+
+func (c *LRU) Delete(key string) {
+	s := c.shard(key)
+	s.mutex.Lock()
+	if element, ok := s.items[key]; ok {
+		s.remove(element)
+	}
+	s.mutex.Unlock()
+}
+
+func (c *LRU) Clear() {
+	for _, s := range c.shards {
+		s.mutex.Lock()
+		s.items = make(map[string]*list.Element)
+		s.order.Init()
+		s.mutex.Unlock()
+	}
+}
+
+func (c *LRU) Len() int {
+	n := 0
+	for _, s := range c.shards {
+		s.mutex.Lock()
+		n += s.order.Len()
+		s.mutex.Unlock()
+	}
+	return n
+}
+
+func (c *LRU) Hits() uint64 {
+	return atomic.LoadUint64(&c.hits)
+}
+
+func (c *LRU) Misses() uint64 {
+	return atomic.LoadUint64(&c.misses)
+}
+
+func (c *LRU) Evictions() uint64 {
+	return atomic.LoadUint64(&c.evictions)
+}
+
+func (c *LRU) shard(key string) *shard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return c.shards[h.Sum32()%shardCount]
+}
+
+func (c *LRU) expired(e *entry) bool {
+	return !e.expires.IsZero() && time.Now().After(e.expires)
+}
+
+func (s *shard) remove(element *list.Element) {
+	s.order.Remove(element)
+	delete(s.items, element.Value.(*entry).key)
+}
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"fmt"
+	"github.com/kzon/technopark-sem2-db/pkg/consts"
+	"sync"
+)
+
+type Cache interface {
+	Len() int
+	Hits() uint64
+	Misses() uint64
+	Evictions() uint64
+	Clear()
+}
+
+type Stats struct {
+	Size      int    `json:"size"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+}
+
+// Registry knows every cache by name, so they can be reported on and flushed together.
+type Registry struct {
+	mutex  sync.RWMutex
+	caches map[string]Cache
+}
+
+func NewRegistry() *Registry {
+	return &Registry{caches: make(map[string]Cache)}
+}
+
+func (r *Registry) Register(name string, c Cache) {
+	r.mutex.Lock()
+	r.caches[name] = c
+	r.mutex.Unlock()
+}
+
+func (r *Registry) Stats() map[string]Stats {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	stats := make(map[string]Stats, len(r.caches))
+	for name, c := range r.caches {
+		stats[name] = Stats{Size: c.Len(), Hits: c.Hits(), Misses: c.Misses(), Evictions: c.Evictions()}
+	}
+	return stats
+}
+
+func (r *Registry) Flush(name string) error {
+	r.mutex.RLock()
+	c, ok := r.caches[name]
+	r.mutex.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: unknown cache '%s'", consts.ErrNotFound, name)
+	}
+	c.Clear()
+	return nil
+}
+
+func (r *Registry) FlushAll() {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for _, c := range r.caches {
+		c.Clear()
+	}
+}
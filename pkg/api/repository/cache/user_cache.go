@@ -3,67 +3,82 @@ package cache
 import (
 	"github.com/kzon/technopark-sem2-db/pkg/consts"
 	"strings"
-	"sync"
+	"time"
 )
 
-type UserCache struct {
-	nickByID      map[int]string
-	nickByIDMutex sync.RWMutex
-
-	idByNick      map[string]int
-	idByNickMutex sync.RWMutex
+type cachedUser struct {
+	id   int
+	nick string
 }
 
-func NewUserCache() UserCache {
-	return UserCache{
-		nickByID: make(map[int]string),
-		idByNick: make(map[string]int),
-	}
+// UserCache maps a nickname in any case to the user id and canonical nickname.
+// Every invalidation bumps the generation, so a lookup that raced with a rename or deletion is not cached.
+type UserCache struct {
+	users      *LRU
+	generation generation
 }
 
-func (u *UserCache) GetIDByNick(nick string) (int, error) {
-	u.idByNickMutex.RLock()
-	id, ok := u.idByNick[strings.ToLower(nick)]
-	u.idByNickMutex.RUnlock()
-	if !ok {
-		return 0, consts.ErrNotFound
-	}
-	return id, nil
+func NewUserCache(size int, ttl time.Duration) *UserCache {
+	return &UserCache{users: NewLRU(size, ttl)}
 }
 
-func (u *UserCache) GetNickByID(id int) (string, error) {
-	u.nickByIDMutex.RLock()
-	nick, ok := u.nickByID[id]
-	u.nickByIDMutex.RUnlock()
-	if !ok {
-		return "", consts.ErrNotFound
+func (u *UserCache) GetIDByNick(nick string) (int, error) {
+	user, err := u.get(nick)
+	if err != nil {
+		return 0, err
 	}
-	return nick, nil
+	return user.id, nil
 }
 
 func (u *UserCache) GetNickCaseInsensitive(nick string) (string, error) {
-	u.idByNickMutex.RLock()
-	id, ok := u.idByNick[strings.ToLower(nick)]
-	u.idByNickMutex.RUnlock()
-	if !ok {
-		return "", consts.ErrNotFound
+	user, err := u.get(nick)
+	if err != nil {
+		return "", err
 	}
-	return u.GetNickByID(id)
+	return user.nick, nil
 }
 
 func (u *UserCache) Add(id int, nick string) {
-	u.addNick(nick, id)
-	u.addID(id, nick)
+	u.users.Set(strings.ToLower(nick), cachedUser{id: id, nick: nick})
+}
+
+// Generation must be taken before the database lookup whose result is passed to AddSince.
+func (u *UserCache) Generation() uint64 {
+	return u.generation.current()
+}
+
+func (u *UserCache) AddSince(generation uint64, id int, nick string) {
+	u.generation.addSince(generation, func() { u.Add(id, nick) })
+}
+
+func (u *UserCache) Invalidate(nick string) {
+	u.generation.invalidate(func() { u.users.Delete(strings.ToLower(nick)) })
+}
+
+func (u *UserCache) Clear() {
+	u.generation.invalidate(u.users.Clear)
+}
+
+func (u *UserCache) Len() int {
+	return u.users.Len()
 }
 
-func (u *UserCache) addNick(nick string, id int) {
-	u.idByNickMutex.Lock()
-	u.idByNick[strings.ToLower(nick)] = id
-	u.idByNickMutex.Unlock()
+func (u *UserCache) Hits() uint64 {
+	return u.users.Hits()
 }
 
-func (u *UserCache) addID(id int, nick string) {
-	u.nickByIDMutex.Lock()
-	u.nickByID[id] = nick
-	u.nickByIDMutex.Unlock()
+func (u *UserCache) Misses() uint64 {
+	return u.users.Misses()
+}
+
+func (u *UserCache) Evictions() uint64 {
+	return u.users.Evictions()
+}
+
+func (u *UserCache) get(nick string) (cachedUser, error) {
+	user, ok := u.users.Get(strings.ToLower(nick))
+	if !ok {
+		return cachedUser{}, consts.ErrNotFound
+	}
+	return user.(cachedUser), nil
 }
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"github.com/kzon/technopark-sem2-db/pkg/consts"
+	"github.com/kzon/technopark-sem2-db/pkg/model"
+	"time"
+)
+
+// UserExport reads everything tied to one user from a single snapshot, row by row.
+// It holds a connection until Close, so at most MaxExports of them run at once.
+type UserExport struct {
+	tx      pgx.Tx
+	release func()
+	User    *model.User
+}
+
+func (r *Repository) BeginUserExport(nickname string) (*UserExport, error) {
+	defer observe("BeginUserExport", time.Now())
+	select {
+	case r.exports <- struct{}{}:
+	default:
+		return nil, fmt.Errorf("%w: too many exports in progress", consts.ErrBusy)
+	}
+	release := func() { <-r.exports }
+	ctx := context.Background()
+	tx, err := r.replicas.reader(keyUsers, authorKey(nickname)).BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		release()
+		return nil, err
+	}
+	user, err := scanUser(tx.QueryRow(ctx, stmtUserByNickname, nickname))
+	if err != nil {
+		tx.Rollback(ctx)
+		release()
+		return nil, err
+	}
+	return &UserExport{tx: tx, release: release, User: user}, nil
+}
+
+func (e *UserExport) Forums(each func(*model.Forum) error) error {
+	return e.each(`select `+forumColumns+` from forum where "user" = $1 order by slug`, func(row pgx.Row) error {
+		forum, err := scanForum(row)
+		if err != nil {
+			return err
+		}
+		return each(forum)
+	})
+}
+
+func (e *UserExport) Threads(each func(*model.Thread) error) error {
+	return e.each(`select `+threadColumns+` from thread where author = $1 order by created, id`, func(row pgx.Row) error {
+		thread, err := scanThread(row)
+		if err != nil {
+			return err
+		}
+		return each(thread)
+	})
+}
+
+func (e *UserExport) Posts(each func(*model.Post) error) error {
+	return e.each(`select `+postColumns+` from post where author = $1 order by created, id`, func(row pgx.Row) error {
+		post, err := scanPost(row)
+		if err != nil {
+			return err
+		}
+		return each(post)
+	})
+}
+
+func (e *UserExport) Votes(each func(*model.Vote) error) error {
+	return e.each(`select id, thread, nickname, voice from vote where nickname = $1 order by thread`, func(row pgx.Row) error {
+		vote := model.Vote{}
+		if err := row.Scan(&vote.ID, &vote.Thread, &vote.Nickname, &vote.Voice); err != nil {
+			return err
+		}
+		return each(&vote)
+	})
+}
+
+func (e *UserExport) each(query string, scan func(row pgx.Row) error) error {
+	rows, err := e.tx.Query(context.Background(), query, e.User.Nickname)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (e *UserExport) Close() error {
+	defer e.release()
+	return e.tx.Rollback(context.Background())
+}
@@ -1,55 +1,63 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 	"github.com/kzon/technopark-sem2-db/pkg/model"
 	"github.com/kzon/technopark-sem2-db/pkg/repository"
-	"strings"
+	"time"
 )
 
 func (r *Repository) GetForumByID(id int) (*model.Forum, error) {
-	return r.getForum("*", "id=$1", id)
+	defer observe("GetForumByID", time.Now())
+	return r.getForum(`select `+forumColumns+` from forum where id = $1`, id)
 }
 
 func (r *Repository) GetForumBySlug(slug string) (*model.Forum, error) {
-	return r.getForum("*", "slug=$1", slug)
+	defer observe("GetForumBySlug", time.Now())
+	return r.getForum(stmtForumBySlug, slug)
 }
 
 func (r *Repository) GetForumSlug(slug string) (*model.Forum, error) {
-	return r.getForum("slug", "slug=$1", slug)
-}
-
-func (r *Repository) getForum(fields, filter string, params ...interface{}) (*model.Forum, error) {
+	defer observe("GetForumSlug", time.Now())
+	if canonical, err := r.forums.GetSlug(slug); err == nil {
+		return &model.Forum{Slug: canonical}, nil
+	}
+	generation := r.forums.Generation()
 	forum := model.Forum{}
-	err := r.db.Get(&forum, `select `+fields+` from forum where `+filter, params...)
+	err := r.db.QueryRow(context.Background(), stmtForumSlug, slug).Scan(&forum.Slug)
 	if err != nil {
 		return nil, repository.Error(err)
 	}
-	var includePosts = fields == "*" || strings.Contains(fields, "posts")
-	if forum.Posts != 0 || !includePosts {
-		return &forum, nil
-	}
-	if forum.Posts, err = r.countForumPosts(forum.Slug); err != nil {
-		return nil, err
-	}
-	if err := r.updateForumPostsCount(forum.ID, forum.Posts); err != nil {
-		return nil, err
-	}
+	r.forums.AddSince(generation, forum.Slug)
 	return &forum, nil
 }
 
+func (r *Repository) getForum(query string, params ...interface{}) (*model.Forum, error) {
+	return scanForum(r.db.QueryRow(context.Background(), query, params...))
+}
+
 func (r *Repository) CreateForum(title, slug, user string) (*model.Forum, error) {
+	defer observe("CreateForum", time.Now())
+	generation := r.forums.Generation()
 	var id int
 	err := r.db.
-		QueryRow(`insert into forum (title, slug, "user") values ($1, $2, $3) returning id`, title, slug, user).
+		QueryRow(
+			context.Background(),
+			`insert into forum (title, slug, "user") select $1, $2, nickname from "user" where nickname = $3 for share returning id`,
+			title, slug, user,
+		).
 		Scan(&id)
 	if err != nil {
-		return nil, err
+		return nil, repository.Error(err)
 	}
+	r.forums.AddSince(generation, slug)
+	r.replicas.wrote(keyStatus, authorKey(user))
 	return r.GetForumByID(id)
 }
 
 func (r *Repository) GetForumUsers(forumSlug, since string, limit int, desc bool) (model.Users, error) {
+	defer observe("GetForumUsers", time.Now())
 	forum, err := r.GetForumSlug(forumSlug)
 	if err != nil {
 		return nil, err
@@ -63,30 +71,14 @@ func (r *Repository) GetForumUsers(forumSlug, since string, limit int, desc bool
 		}
 	}
 	query := fmt.Sprintf(
-		`select "user".* from "user"
+		`select "user".id, nickname, fullname, about, email from "user"
          		join forum_user on nickname = forum_user.user
 				where forum = $1 %s order by nickname %s %s`,
 		sinceFilter, r.getOrder(desc), r.getLimit(limit),
 	)
-	users := make(model.Users, 0)
+	db := r.replicas.reader(forumKey(forum.Slug), keyUsers)
 	if since == "" {
-		err = r.db.Select(&users, query, forum.Slug)
-	} else {
-		err = r.db.Select(&users, query, forum.Slug, since)
+		return scanUsers(db.Query(context.Background(), query, forum.Slug))
 	}
-	return users, err
-}
-
-func (r *Repository) countForumPosts(forumSlug string) (int, error) {
-	var count int
-	err := r.db.Get(&count, `select count(*) from post where forum=$1`, forumSlug)
-	if err != nil {
-		return 0, err
-	}
-	return count, nil
-}
-
-func (r *Repository) updateForumPostsCount(id, posts int) error {
-	_, err := r.db.Exec(`update forum set posts=$1 where id=$2`, posts, id)
-	return err
+	return scanUsers(db.Query(context.Background(), query, forum.Slug, since))
 }
@@ -0,0 +1,430 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"github.com/kzon/technopark-sem2-db/pkg/consts"
+	"github.com/kzon/technopark-sem2-db/pkg/model"
+	"github.com/kzon/technopark-sem2-db/pkg/repository"
+	"strings"
+	"time"
+)
+
+type IngestBatch struct {
+	Users   model.Users
+	Forums  []*model.Forum
+	Threads model.Threads
+	Posts   model.Posts
+}
+
+func (r *Repository) Ingest(batch *IngestBatch) error {
+	defer observe("Ingest", time.Now())
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	if err := r.ingest(ctx, tx, batch); err != nil {
+		tx.Rollback(ctx)
+		return repository.Error(err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	r.replicas.wrote(keyGlobal)
+	return r.warmUserCache(ctx, batch.Users)
+}
+
+func (r *Repository) ingest(ctx context.Context, tx pgx.Tx, batch *IngestBatch) error {
+	if err := r.copyUsers(ctx, tx, batch.Users); err != nil {
+		return err
+	}
+	if err := r.setIngestAuthors(ctx, tx, batch); err != nil {
+		return err
+	}
+	if err := r.copyForums(ctx, tx, batch.Forums); err != nil {
+		return err
+	}
+	if err := r.setIngestThreadForums(ctx, tx, batch.Threads); err != nil {
+		return err
+	}
+	if err := r.copyThreads(ctx, tx, batch.Threads); err != nil {
+		return err
+	}
+	return r.copyPosts(ctx, tx, batch)
+}
+
+func (r *Repository) copyUsers(ctx context.Context, tx pgx.Tx, users model.Users) error {
+	rows := make([][]interface{}, 0, len(users))
+	for _, u := range users {
+		rows = append(rows, []interface{}{u.Nickname, u.Email, u.Fullname, u.About})
+	}
+	_, err := tx.CopyFrom(ctx, pgx.Identifier{"user"}, []string{"nickname", "email", "fullname", "about"}, pgx.CopyFromRows(rows))
+	return err
+}
+
+// setIngestAuthors replaces the authors of the batch with the nicknames as users have registered them.
+func (r *Repository) setIngestAuthors(ctx context.Context, tx pgx.Tx, batch *IngestBatch) error {
+	authors := make([]string, 0, len(batch.Forums)+len(batch.Threads)+len(batch.Posts))
+	for _, f := range batch.Forums {
+		authors = append(authors, f.User)
+	}
+	for _, t := range batch.Threads {
+		authors = append(authors, t.Author)
+	}
+	for _, p := range batch.Posts {
+		authors = append(authors, p.Author)
+	}
+	nicknames, err := loadIngestNames(ctx, tx, `select nickname from "user" where nickname = any($1::text[]::citext[]) for share`, authors)
+	if err != nil {
+		return err
+	}
+	for _, f := range batch.Forums {
+		if f.User, err = nicknames.get("user", f.User); err != nil {
+			return err
+		}
+	}
+	for _, t := range batch.Threads {
+		if t.Author, err = nicknames.get("user", t.Author); err != nil {
+			return err
+		}
+	}
+	for _, p := range batch.Posts {
+		if p.Author, err = nicknames.get("user", p.Author); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setIngestThreadForums replaces the forums of the batch threads with the slugs as the forums were created.
+func (r *Repository) setIngestThreadForums(ctx context.Context, tx pgx.Tx, threads model.Threads) error {
+	forums := make([]string, 0, len(threads))
+	for _, t := range threads {
+		forums = append(forums, t.Forum)
+	}
+	slugs, err := loadIngestNames(ctx, tx, `select slug from forum where slug = any($1::text[]::citext[])`, forums)
+	if err != nil {
+		return err
+	}
+	for _, t := range threads {
+		if t.Forum, err = slugs.get("forum", t.Forum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ingestNames maps case-insensitive names to their stored spelling.
+type ingestNames map[string]string
+
+func loadIngestNames(ctx context.Context, tx pgx.Tx, query string, names []string) (ingestNames, error) {
+	result := make(ingestNames, len(names))
+	if len(names) == 0 {
+		return result, nil
+	}
+	rows, err := tx.Query(ctx, query, names)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		result[strings.ToLower(name)] = name
+	}
+	return result, rows.Err()
+}
+
+func (n ingestNames) get(kind, name string) (string, error) {
+	stored, ok := n[strings.ToLower(name)]
+	if !ok {
+		return "", fmt.Errorf("%w: %s %s", consts.ErrNotFound, kind, name)
+	}
+	return stored, nil
+}
+
+func (r *Repository) copyForums(ctx context.Context, tx pgx.Tx, forums []*model.Forum) error {
+	rows := make([][]interface{}, 0, len(forums))
+	for _, f := range forums {
+		rows = append(rows, []interface{}{f.Slug, f.Title, f.User})
+	}
+	_, err := tx.CopyFrom(ctx, pgx.Identifier{"forum"}, []string{"slug", "title", "user"}, pgx.CopyFromRows(rows))
+	return err
+}
+
+func (r *Repository) copyThreads(ctx context.Context, tx pgx.Tx, threads model.Threads) error {
+	if err := r.allocateThreadIDs(ctx, tx, threads); err != nil {
+		return err
+	}
+	rows := make([][]interface{}, 0, len(threads))
+	for _, t := range threads {
+		created, err := parseIngestTime(t.Created)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, []interface{}{t.ID, t.Slug, t.Title, t.Author, t.Forum, t.Message, t.Votes, created})
+	}
+	columns := []string{"id", "slug", "title", "author", "forum", "message", "votes", "created"}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"thread"}, columns, pgx.CopyFromRows(rows)); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, `select setval('thread_id_seq', greatest((select max(id) from thread), 1))`)
+	return err
+}
+
+func (r *Repository) allocateThreadIDs(ctx context.Context, tx pgx.Tx, threads model.Threads) error {
+	missing := 0
+	for _, t := range threads {
+		if t.ID == 0 {
+			missing++
+		}
+	}
+	if missing == 0 {
+		return nil
+	}
+	rows, err := tx.Query(ctx, `select nextval('thread_id_seq') from generate_series(1, $1)`, missing)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for _, t := range threads {
+		if t.ID != 0 {
+			continue
+		}
+		if !rows.Next() {
+			return fmt.Errorf("thread id sequence returned less than %d values", missing)
+		}
+		if err := rows.Scan(&t.ID); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *Repository) copyPosts(ctx context.Context, tx pgx.Tx, batch *IngestBatch) error {
+	posts := batch.Posts
+	if err := r.reserveIngestPostIDs(ctx, tx, posts); err != nil {
+		return err
+	}
+	if err := r.allocatePostIDs(posts); err != nil {
+		return err
+	}
+	forums, err := r.getIngestThreadForums(ctx, tx, batch)
+	if err != nil {
+		return err
+	}
+	if err := r.setIngestPostPaths(ctx, tx, posts); err != nil {
+		return err
+	}
+	rows := make([][]interface{}, 0, len(posts))
+	for _, p := range posts {
+		forum, ok := forums[p.Thread]
+		if !ok {
+			return fmt.Errorf("%w: thread %d of post %d", consts.ErrNotFound, p.Thread, p.ID)
+		}
+		if p.Forum != "" && !strings.EqualFold(p.Forum, forum) {
+			return fmt.Errorf("%w: thread %d of post %d is in forum %s", consts.ErrConflict, p.Thread, p.ID, forum)
+		}
+		p.Forum = forum
+		created, err := parseIngestTime(p.Created)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, []interface{}{p.ID, p.Parent, p.Path, p.Author, p.Forum, p.Thread, p.Message, p.IsEdited, created})
+	}
+	columns := []string{"id", "parent", "path", "author", "forum", "thread", "message", "isEdited", "created"}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"post"}, columns, pgx.CopyFromRows(rows))
+	return err
+}
+
+// reserveIngestPostIDs moves the sequence past the explicit post ids of the batch. Ids at or below
+// the last block handed out may already be reserved by a BlockAllocator, so they are rejected.
+func (r *Repository) reserveIngestPostIDs(ctx context.Context, tx pgx.Tx, posts model.Posts) error {
+	lowest, highest := 0, 0
+	for _, p := range posts {
+		if p.ID == 0 {
+			continue
+		}
+		if lowest == 0 || p.ID < lowest {
+			lowest = p.ID
+		}
+		if p.ID > highest {
+			highest = p.ID
+		}
+	}
+	if highest == 0 {
+		return nil
+	}
+	var reserved int
+	err := tx.QueryRow(ctx,
+		`select coalesce(last_value + increment_by - 1, 0) from pg_sequences
+		where schemaname = current_schema() and sequencename = $1`,
+		postIDSequence,
+	).Scan(&reserved)
+	if err != nil {
+		return err
+	}
+	if lowest <= reserved {
+		return fmt.Errorf("%w: post id %d may already be reserved, ids must be above %d", consts.ErrConflict, lowest, reserved)
+	}
+	_, err = tx.Exec(ctx, `select setval('post_id_seq', $1)`, highest)
+	return err
+}
+
+func (r *Repository) allocatePostIDs(posts model.Posts) error {
+	missing := 0
+	for _, p := range posts {
+		if p.ID == 0 {
+			missing++
+		}
+	}
+	if missing == 0 {
+		return nil
+	}
+	ids, err := r.postsIDGenerator.Next(missing)
+	if err != nil {
+		return err
+	}
+	for _, p := range posts {
+		if p.ID == 0 {
+			p.ID, ids = ids[0], ids[1:]
+		}
+	}
+	return nil
+}
+
+func (r *Repository) getIngestThreadForums(ctx context.Context, tx pgx.Tx, batch *IngestBatch) (map[int]string, error) {
+	forums := make(map[int]string, len(batch.Threads))
+	for _, t := range batch.Threads {
+		forums[t.ID] = t.Forum
+	}
+	missing := make([]int, 0)
+	for _, p := range batch.Posts {
+		if _, ok := forums[p.Thread]; !ok {
+			missing = append(missing, p.Thread)
+		}
+	}
+	if len(missing) == 0 {
+		return forums, nil
+	}
+	rows, err := tx.Query(ctx, `select id, forum from thread where id = any($1)`, missing)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var forum string
+		if err := rows.Scan(&id, &forum); err != nil {
+			return nil, err
+		}
+		forums[id] = forum
+	}
+	return forums, rows.Err()
+}
+
+// setIngestPostPaths resolves parents from the batch itself first, so replies may precede their parents in the input.
+func (r *Repository) setIngestPostPaths(ctx context.Context, tx pgx.Tx, posts model.Posts) error {
+	paths := make(map[int]model.Path, len(posts))
+	threads := make(map[int]int, len(posts))
+	ingested := make(map[int]bool, len(posts))
+	for _, p := range posts {
+		ingested[p.ID] = true
+		threads[p.ID] = p.Thread
+		if p.Parent == 0 {
+			p.Path = model.Path{p.ID}
+			paths[p.ID] = p.Path
+		}
+	}
+	missing := make([]int, 0)
+	for _, p := range posts {
+		if p.Parent != 0 && !ingested[p.Parent] {
+			missing = append(missing, p.Parent)
+		}
+	}
+	if len(missing) > 0 {
+		if err := r.loadIngestPaths(ctx, tx, missing, paths, threads); err != nil {
+			return err
+		}
+	}
+	for _, p := range posts {
+		if thread, ok := threads[p.Parent]; ok && thread != p.Thread {
+			return fmt.Errorf("%w: parent %d of post %d was created in another thread", consts.ErrConflict, p.Parent, p.ID)
+		}
+	}
+	for resolved := true; resolved; {
+		resolved = false
+		for _, p := range posts {
+			if p.Path != nil {
+				continue
+			}
+			if parent, ok := paths[p.Parent]; ok {
+				p.Path = parent.Child(p.ID)
+				paths[p.ID] = p.Path
+				resolved = true
+			}
+		}
+	}
+	for _, p := range posts {
+		if p.Path == nil {
+			return fmt.Errorf("%w: parent %d of post %d do not exists", consts.ErrConflict, p.Parent, p.ID)
+		}
+	}
+	return nil
+}
+
+func (r *Repository) loadIngestPaths(
+	ctx context.Context, tx pgx.Tx, ids []int, paths map[int]model.Path, threads map[int]int,
+) error {
+	rows, err := tx.Query(ctx, `select id, thread, path from post where id = any($1)`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, thread int
+		var path model.Path
+		if err := rows.Scan(&id, &thread, &path); err != nil {
+			return err
+		}
+		paths[id] = path
+		threads[id] = thread
+	}
+	return rows.Err()
+}
+
+func (r *Repository) warmUserCache(ctx context.Context, users model.Users) error {
+	if len(users) == 0 {
+		return nil
+	}
+	nicknames := make([]string, 0, len(users))
+	for _, u := range users {
+		nicknames = append(nicknames, u.Nickname)
+	}
+	generation := r.users.Generation()
+	rows, err := r.db.Query(ctx, stmtUserNicknames, nicknames)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var nickname string
+		if err := rows.Scan(&id, &nickname); err != nil {
+			return err
+		}
+		r.users.AddSince(generation, id, nickname)
+	}
+	return rows.Err()
+}
+
+func parseIngestTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Now(), nil
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"github.com/kzon/technopark-sem2-db/pkg/api/repository/cache"
+	"github.com/kzon/technopark-sem2-db/pkg/metrics"
+	"time"
+)
+
+var queryDuration = metrics.NewHistogramVec(
+	"repository_query_duration_seconds", "Repository method latency.", metrics.DefaultBuckets, "method",
+)
+
+func init() {
+	metrics.Register(queryDuration)
+}
+
+func observe(method string, start time.Time) {
+	queryDuration.Observe(time.Since(start).Seconds(), method)
+}
+
+func (r *Repository) Collectors() []metrics.Collector {
+	return []metrics.Collector{
+		metrics.NewGaugeFunc("db_pool_max_conns", "Maximum size of the postgres pool.", func() float64 {
+			return float64(r.db.Stat().MaxConns())
+		}),
+		metrics.NewGaugeFunc("db_pool_total_conns", "Open postgres connections.", func() float64 {
+			return float64(r.db.Stat().TotalConns())
+		}),
+		metrics.NewGaugeFunc("db_pool_acquired_conns", "Postgres connections in use.", func() float64 {
+			return float64(r.db.Stat().AcquiredConns())
+		}),
+		metrics.NewGaugeFunc("db_pool_idle_conns", "Idle postgres connections.", func() float64 {
+			return float64(r.db.Stat().IdleConns())
+		}),
+		metrics.NewCounterFunc("db_pool_acquire_total", "Postgres connection acquisitions.", func() float64 {
+			return float64(r.db.Stat().AcquireCount())
+		}),
+		metrics.NewCounterFunc("db_pool_empty_acquire_total", "Acquisitions that waited for a free connection.", func() float64 {
+			return float64(r.db.Stat().EmptyAcquireCount())
+		}),
+		metrics.NewCounterFunc("db_pool_acquire_duration_seconds_total", "Time spent acquiring connections.", func() float64 {
+			return r.db.Stat().AcquireDuration().Seconds()
+		}),
+		metrics.NewGaugeVecFunc("replica_lag_seconds", "Last measured replay lag of each replica, -1 when unknown.", "replica", r.replicas.lags),
+		metrics.NewGaugeVecFunc("cache_size", "Entries held by each cache.", "cache", func() map[string]float64 {
+			return r.cacheStat(func(s cache.Stats) float64 { return float64(s.Size) })
+		}),
+		metrics.NewCounterVecFunc("cache_hits_total", "Cache lookups served from memory.", "cache", func() map[string]float64 {
+			return r.cacheStat(func(s cache.Stats) float64 { return float64(s.Hits) })
+		}),
+		metrics.NewCounterVecFunc("cache_misses_total", "Cache lookups that missed.", "cache", func() map[string]float64 {
+			return r.cacheStat(func(s cache.Stats) float64 { return float64(s.Misses) })
+		}),
+		metrics.NewCounterVecFunc("cache_evictions_total", "Entries evicted to respect the cache size.", "cache", func() map[string]float64 {
+			return r.cacheStat(func(s cache.Stats) float64 { return float64(s.Evictions) })
+		}),
+	}
+}
+
+func (r *Repository) cacheStat(value func(cache.Stats) float64) map[string]float64 {
+	stats := r.caches.Stats()
+	values := make(map[string]float64, len(stats))
+	for name, s := range stats {
+		values[name] = value(s)
+	}
+	return values
+}
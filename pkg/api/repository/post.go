@@ -1,12 +1,14 @@
 package repository
 
 import (
+	"context"
 	"fmt"
-	"github.com/jmoiron/sqlx"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
 	apiModel "github.com/kzon/technopark-sem2-db/pkg/api/model"
+	"github.com/kzon/technopark-sem2-db/pkg/consts"
 	"github.com/kzon/technopark-sem2-db/pkg/model"
 	"github.com/kzon/technopark-sem2-db/pkg/repository"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -15,79 +17,140 @@ const (
 	SortFlat       = "flat"
 	SortTree       = "tree"
 	SortParentTree = "parent_tree"
-
-	pathDelim    = "."
-	maxIDLength  = 7
-	maxTreeLevel = 5
-
-	postChunkSize = 50
 )
 
-var zeroPathStud = strings.Repeat("0", maxIDLength)
-
 func (r *Repository) GetPostByID(id int) (*model.Post, error) {
-	return r.getPost("id=$1", id)
+	defer observe("GetPostByID", time.Now())
+	return scanPost(r.db.QueryRow(context.Background(), stmtPostByID, id))
 }
 
-func (r *Repository) getPost(filter string, params ...interface{}) (*model.Post, error) {
-	return r.getPostFields("*", filter, params...)
+func (r *Repository) GetPostParents(ids []int) (map[int]*model.Post, error) {
+	defer observe("GetPostParents", time.Now())
+	parents := make(map[int]*model.Post, len(ids))
+	if len(ids) == 0 {
+		return parents, nil
+	}
+	rows, err := r.db.Query(context.Background(), stmtPostParents, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		post := &model.Post{}
+		if err := rows.Scan(&post.ID, &post.Thread, &post.Path); err != nil {
+			return nil, err
+		}
+		parents[post.ID] = post
+	}
+	return parents, rows.Err()
 }
 
-func (r *Repository) getPostFields(fields, filter string, params ...interface{}) (*model.Post, error) {
-	p := model.Post{}
-	err := r.db.Get(&p, "select "+fields+" from post where "+filter, params...)
-	if err != nil {
-		return nil, repository.Error(err)
+func (r *Repository) getPostsByIDs(tx pgx.Tx, ids []int) (model.Posts, error) {
+	return scanPosts(tx.Query(context.Background(), `select `+postColumns+` from post where id = any($1) order by id`, ids))
+}
+
+func (r *Repository) getPosts(db *pgxpool.Pool, orderBy []string, limit int, filter string, params ...interface{}) (model.Posts, error) {
+	return scanPosts(db.Query(context.Background(), r.getPostsQuery(orderBy, limit, filter), params...))
+}
+
+func (r *Repository) getPostsQuery(orderBy []string, limit int, filter string) string {
+	query := fmt.Sprintf(`select `+postColumns+` from post where %s order by %s`, filter, strings.Join(orderBy, ","))
+	if limit > 0 {
+		query += fmt.Sprintf(" limit %d", limit)
 	}
-	return &p, nil
+	return query
 }
 
-func (r *Repository) getPostsByIDs(ids []int) (model.Posts, error) {
-	posts := make(model.Posts, 0)
-	query, args, err := sqlx.In(`select * from post where id in (?) order by id`, ids)
+// getPostsSince runs a query paging posts after the post passed as parameter sinceParam. A placeholder row
+// is appended in the same statement when that post does not exist, so an unknown since is told from an empty page.
+func (r *Repository) getPostsSince(db *pgxpool.Pool, query string, sinceParam int, params ...interface{}) (model.Posts, error) {
+	query = fmt.Sprintf(
+		`select true, page.* from (%s) page
+		union all select false, 0, 0, '', '', 0, '', false, now(), now() where not exists (select 1 from post where id = $%d)`,
+		query, sinceParam,
+	)
+	rows, err := db.Query(context.Background(), query, params...)
 	if err != nil {
 		return nil, err
 	}
-	query = r.db.Rebind(query)
-	err = r.db.Select(&posts, query, args...)
-	return posts, err
+	defer rows.Close()
+	posts := make(model.Posts, 0)
+	for rows.Next() {
+		var found bool
+		p := model.Post{}
+		var created time.Time
+		if err := rows.Scan(append([]interface{}{&found}, postFields(&p, &created)...)...); err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: post %d", consts.ErrNotFound, params[sinceParam-1])
+		}
+		p.Created = formatTime(created)
+		posts = append(posts, &p)
+	}
+	return posts, rows.Err()
 }
 
-func (r *Repository) getPosts(orderBy []string, limit int, filter string, params ...interface{}) (model.Posts, error) {
-	query := fmt.Sprintf(`select * from post where %s order by %s`, filter, strings.Join(orderBy, ","))
-	if limit > 0 {
-		query += fmt.Sprintf(" limit %d", limit)
+func (r *Repository) CreatePosts(posts []*apiModel.PostCreate, thread *model.Thread, parents map[int]*model.Post) (model.Posts, error) {
+	defer observe("CreatePosts", time.Now())
+	forum, err := r.GetForumSlug(thread.Forum)
+	if err != nil {
+		return nil, err
 	}
-	posts := make(model.Posts, 0, limit)
-	err := r.db.Select(&posts, query, params...)
-	return posts, err
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := r.createPosts(tx, forum, thread, posts, parents)
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, repository.Error(err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	keys := []string{threadKey(thread.ID), forumKey(forum.Slug), keyStatus}
+	for _, post := range posts {
+		keys = append(keys, authorKey(post.Author))
+	}
+	r.replicas.wrote(keys...)
+	return result, nil
 }
 
-func (r *Repository) CreatePosts(posts []*apiModel.PostCreate, thread *model.Thread) (model.Posts, error) {
-	forum, err := r.GetForumSlug(thread.Forum)
+func (r *Repository) createPosts(
+	tx pgx.Tx, forum *model.Forum, thread *model.Thread, posts []*apiModel.PostCreate, parents map[int]*model.Post,
+) (model.Posts, error) {
+	if len(posts) == 0 {
+		return make(model.Posts, 0), nil
+	}
+	authors := make([]string, 0, len(posts))
+	for _, post := range posts {
+		authors = append(authors, post.Author)
+	}
+	nicknames, err := r.lockAuthors(context.Background(), tx, authors)
 	if err != nil {
 		return nil, err
 	}
+	for _, post := range posts {
+		post.Author = nicknames[strings.ToLower(post.Author)]
+	}
 	now := time.Now()
-	result := make(model.Posts, 0, len(posts))
+	ids := make([]int, 0, len(posts))
 	for _, chunk := range r.chunkPosts(posts) {
-		createdIDs, err := r.createPostsChunk(forum, thread, chunk, now)
-		if err != nil {
-			return nil, err
-		}
-		created, err := r.getPostsByIDs(createdIDs)
+		createdIDs, err := r.createPostsChunk(tx, forum, thread, chunk, parents, now)
 		if err != nil {
 			return nil, err
 		}
-		result = append(result, created...)
+		ids = append(ids, createdIDs...)
 	}
-	return result, nil
+	return r.getPostsByIDs(tx, ids)
 }
 
 func (r *Repository) chunkPosts(posts []*apiModel.PostCreate) [][]*apiModel.PostCreate {
 	chunked := make([][]*apiModel.PostCreate, 0)
-	for i := 0; i < len(posts); i += postChunkSize {
-		end := i + postChunkSize
+	for i := 0; i < len(posts); i += r.postChunkSize {
+		end := i + r.postChunkSize
 		if end > len(posts) {
 			end = len(posts)
 		}
@@ -96,14 +159,19 @@ func (r *Repository) chunkPosts(posts []*apiModel.PostCreate) [][]*apiModel.Post
 	return chunked
 }
 
-func (r *Repository) createPostsChunk(forum *model.Forum, thread *model.Thread, posts []*apiModel.PostCreate, created time.Time) ([]int, error) {
+func (r *Repository) createPostsChunk(
+	tx pgx.Tx, forum *model.Forum, thread *model.Thread, posts []*apiModel.PostCreate, parents map[int]*model.Post, created time.Time,
+) ([]int, error) {
 	columns := 8
 	placeholders := make([]string, 0, len(posts))
 	args := make([]interface{}, 0, len(posts)*columns)
-	ids := r.postsIDGenerator.Next(len(posts))
+	ids, err := r.postsIDGenerator.Next(len(posts))
+	if err != nil {
+		return nil, err
+	}
 	for i, post := range posts {
 		id := ids[i]
-		path, err := r.getPostPath(id, post.Parent)
+		path, err := r.getPostPath(id, post.Parent, parents)
 		if err != nil {
 			return nil, err
 		}
@@ -117,40 +185,26 @@ func (r *Repository) createPostsChunk(forum *model.Forum, thread *model.Thread,
 		"insert into post (id, thread, forum, parent, path, author, message, created) values %s",
 		strings.Join(placeholders, ","),
 	)
-	_, err := r.db.Exec(query, args...)
+	_, err = tx.Exec(context.Background(), query, args...)
 	return ids, err
 }
 
-func (r *Repository) getPostPath(id, parentID int) (string, error) {
-	var base string
+func (r *Repository) getPostPath(id, parentID int, parents map[int]*model.Post) (model.Path, error) {
 	if parentID == 0 {
-		base = r.getZeroPostPath()
-	} else {
-		parent, err := r.getPostFields("path", "id=$1", parentID)
-		if err != nil {
-			return "", err
-		}
-		base = parent.Path
+		return model.Path{id}, nil
 	}
-	path := strings.Replace(base, zeroPathStud, r.padPostID(id), 1)
-	return path, nil
-}
-
-func (r *Repository) getZeroPostPath() string {
-	path := zeroPathStud
-	for i := 0; i < maxTreeLevel-1; i++ {
-		path += pathDelim + zeroPathStud
+	parent, ok := parents[parentID]
+	if !ok {
+		return nil, fmt.Errorf("%w: post parent do not exists", consts.ErrConflict)
 	}
-	return path
-}
-
-func (r *Repository) padPostID(id int) string {
-	return fmt.Sprintf("%0"+strconv.Itoa(maxIDLength)+"d", id)
+	return parent.Path.Child(id), nil
 }
 
 func (r *Repository) UpdatePostMessage(id int, message string) (*model.Post, error) {
+	defer observe("UpdatePostMessage", time.Now())
 	if message != "" {
 		_, err := r.db.Exec(
+			context.Background(),
 			`update post set "message" = $1, "isEdited" = true where id = $2 and "message" <> $1`,
 			message, id,
 		)
@@ -158,10 +212,9 @@ func (r *Repository) UpdatePostMessage(id int, message string) (*model.Post, err
 			return nil, err
 		}
 	}
-	return r.GetPostByID(id)
-}
-
-func (r *Repository) updatePostPath(tx *sqlx.Tx, id int, path string) error {
-	_, err := tx.Exec(`update post set path = $1 where id = $2`, path, id)
-	return err
+	post, err := r.GetPostByID(id)
+	if err == nil && message != "" {
+		r.replicas.wrote(threadKey(post.Thread), authorKey(post.Author))
+	}
+	return post, err
 }
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/kzon/technopark-sem2-db/pkg/metrics"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var readRoutes = metrics.NewCounterVec(
+	"repository_read_routes_total", "Replica-eligible reads by the pool that served them.", "route",
+)
+
+func init() {
+	metrics.Register(readRoutes)
+}
+
+const (
+	routeReplica       = "replica"
+	routePrimarySticky = "primary_sticky"
+	routePrimaryLag    = "primary_lag"
+
+	// keyGlobal is written by bulk operations and makes every replica-eligible read sticky.
+	keyGlobal = "global"
+	keyStatus = "status"
+	keyUsers  = "users"
+)
+
+const replicaLagQuery = `select case
+	when not pg_is_in_recovery() or pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() then 0
+	else coalesce(extract(epoch from now() - pg_last_xact_replay_timestamp()), 0)
+end`
+
+type replica struct {
+	pool *pgxpool.Pool
+	// lag is the last measured replay lag in nanoseconds, negative until measured or when unreachable.
+	lag int64
+}
+
+// replicaSet routes reads to replicas unless they lag behind or the data read was written recently.
+// Writes are remembered in process memory, so read-your-writes only holds for clients of a single instance:
+// behind a load balancer a read may reach an instance that did not see the write and go to a lagging replica.
+type replicaSet struct {
+	primary    *pgxpool.Pool
+	replicas   []*replica
+	next       uint32
+	maxLag     time.Duration
+	stickiness time.Duration
+
+	mutex   sync.Mutex
+	written map[string]time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newReplicaSet(primary *pgxpool.Pool, pools []*pgxpool.Pool, maxLag, stickiness time.Duration) *replicaSet {
+	s := &replicaSet{
+		primary:    primary,
+		maxLag:     maxLag,
+		stickiness: stickiness,
+		written:    make(map[string]time.Time),
+	}
+	for _, pool := range pools {
+		s.replicas = append(s.replicas, &replica{pool: pool, lag: -1})
+	}
+	return s
+}
+
+// reader picks the pool for a read of data identified by keys.
+func (s *replicaSet) reader(keys ...string) *pgxpool.Pool {
+	if len(s.replicas) == 0 {
+		return s.primary
+	}
+	if s.recentlyWritten(keys) {
+		readRoutes.Inc(routePrimarySticky)
+		return s.primary
+	}
+	start := atomic.AddUint32(&s.next, 1)
+	for i := range s.replicas {
+		r := s.replicas[(int(start)+i)%len(s.replicas)]
+		lag := time.Duration(atomic.LoadInt64(&r.lag))
+		if lag >= 0 && lag <= s.maxLag {
+			readRoutes.Inc(routeReplica)
+			return r.pool
+		}
+	}
+	readRoutes.Inc(routePrimaryLag)
+	return s.primary
+}
+
+// wrote makes reads of keys go to the primary for the stickiness window, so writers see their own writes.
+func (s *replicaSet) wrote(keys ...string) {
+	if len(s.replicas) == 0 {
+		return
+	}
+	now := time.Now()
+	s.mutex.Lock()
+	for _, key := range keys {
+		s.written[key] = now
+	}
+	s.mutex.Unlock()
+}
+
+func (s *replicaSet) recentlyWritten(keys []string) bool {
+	deadline := time.Now().Add(-s.stickiness)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.written[keyGlobal].After(deadline) {
+		return true
+	}
+	for _, key := range keys {
+		if s.written[key].After(deadline) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *replicaSet) start(period time.Duration) {
+	if len(s.replicas) == 0 || s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		for {
+			s.checkLag(period)
+			s.forget()
+			select {
+			case <-ticker.C:
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (s *replicaSet) shutdown(ctx context.Context) error {
+	if s.stop == nil {
+		return nil
+	}
+	close(s.stop)
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *replicaSet) checkLag(timeout time.Duration) {
+	for i, r := range s.replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		var seconds float64
+		err := r.pool.QueryRow(ctx, replicaLagQuery).Scan(&seconds)
+		cancel()
+		if err != nil {
+			if atomic.SwapInt64(&r.lag, -1) >= 0 {
+				log.Printf("replica %d: lag check failed: %s", i, err)
+			}
+			continue
+		}
+		atomic.StoreInt64(&r.lag, int64(seconds*float64(time.Second)))
+	}
+}
+
+func (s *replicaSet) forget() {
+	deadline := time.Now().Add(-s.stickiness)
+	s.mutex.Lock()
+	for key, at := range s.written {
+		if at.Before(deadline) {
+			delete(s.written, key)
+		}
+	}
+	s.mutex.Unlock()
+}
+
+func (s *replicaSet) lags() map[string]float64 {
+	lags := make(map[string]float64, len(s.replicas))
+	for i, r := range s.replicas {
+		lag := time.Duration(atomic.LoadInt64(&r.lag))
+		if lag < 0 {
+			lags[strconv.Itoa(i)] = -1
+			continue
+		}
+		lags[strconv.Itoa(i)] = lag.Seconds()
+	}
+	return lags
+}
+
+func forumKey(slug string) string {
+	return "forum:" + strings.ToLower(slug)
+}
+
+func authorKey(nickname string) string {
+	return "author:" + strings.ToLower(nickname)
+}
+
+func threadKey(id int) string {
+	return "thread:" + strconv.Itoa(id)
+}
@@ -1,23 +1,83 @@
 package repository
 
 import (
+	"context"
 	"fmt"
-	"github.com/jmoiron/sqlx"
+	"github.com/jackc/pgx/v4/pgxpool"
+	apiModel "github.com/kzon/technopark-sem2-db/pkg/api/model"
 	"github.com/kzon/technopark-sem2-db/pkg/api/repository/cache"
 	"github.com/kzon/technopark-sem2-db/pkg/api/repository/sequence"
+	"github.com/kzon/technopark-sem2-db/pkg/config"
+	"time"
 )
 
 type Repository struct {
-	db               *sqlx.DB
-	users            cache.UserCache
-	postsIDGenerator sequence.Generator
+	db               *pgxpool.Pool
+	users            *cache.UserCache
+	forums           *cache.ForumCache
+	threads          *cache.ThreadCache
+	caches           *cache.Registry
+	replicas         *replicaSet
+	lagCheckPeriod   time.Duration
+	postsIDGenerator sequence.Allocator
+	postChunkSize    int
+	exports          chan struct{}
 }
 
-func NewRepository(db *sqlx.DB) Repository {
-	return Repository{
+const postIDSequence = "post_id_seq"
+
+func NewRepository(db *pgxpool.Pool, replicas []*pgxpool.Pool, cfg config.Repository) Repository {
+	r := Repository{
 		db:               db,
-		users:            cache.NewUserCache(),
-		postsIDGenerator: sequence.NewGenerator(),
+		replicas:         newReplicaSet(db, replicas, cfg.ReplicaMaxLag.Duration, cfg.ReadStickiness.Duration),
+		lagCheckPeriod:   cfg.ReplicaLagCheckPeriod.Duration,
+		users:            cache.NewUserCache(cfg.UserCacheSize, cfg.UserCacheTTL.Duration),
+		forums:           cache.NewForumCache(cfg.ForumCacheSize, cfg.IdentityCacheTTL.Duration),
+		threads:          cache.NewThreadCache(cfg.ThreadCacheSize, cfg.IdentityCacheTTL.Duration),
+		caches:           cache.NewRegistry(),
+		postsIDGenerator: sequence.NewBlockAllocator(db, postIDSequence),
+		postChunkSize:    cfg.PostChunkSize,
+		exports:          make(chan struct{}, cfg.MaxExports),
+	}
+	r.caches.Register("user", r.users)
+	r.caches.Register("forum", r.forums)
+	r.caches.Register("thread", r.threads)
+	return r
+}
+
+// StartReplicaMonitor starts polling replica lag; until the first poll all reads go to the primary.
+func (r *Repository) StartReplicaMonitor() {
+	r.replicas.start(r.lagCheckPeriod)
+}
+
+func (r *Repository) StopReplicaMonitor(ctx context.Context) error {
+	return r.replicas.shutdown(ctx)
+}
+
+func (r *Repository) CacheStats() map[string]cache.Stats {
+	return r.caches.Stats()
+}
+
+func (r *Repository) FlushCache(name string) error {
+	return r.caches.Flush(name)
+}
+
+func (r *Repository) FlushCaches() {
+	r.caches.FlushAll()
+}
+
+func (r *Repository) PoolStat() apiModel.PoolStat {
+	stat := r.db.Stat()
+	return apiModel.PoolStat{
+		AcquireCount:         stat.AcquireCount(),
+		AcquireDuration:      stat.AcquireDuration().Seconds(),
+		AcquiredConns:        stat.AcquiredConns(),
+		CanceledAcquireCount: stat.CanceledAcquireCount(),
+		ConstructingConns:    stat.ConstructingConns(),
+		EmptyAcquireCount:    stat.EmptyAcquireCount(),
+		IdleConns:            stat.IdleConns(),
+		MaxConns:             stat.MaxConns(),
+		TotalConns:           stat.TotalConns(),
 	}
 }
 
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"github.com/jackc/pgx/v4"
+	"github.com/kzon/technopark-sem2-db/pkg/model"
+	"github.com/kzon/technopark-sem2-db/pkg/repository"
+	"time"
+)
+
+const (
+	userColumns   = `id, nickname, fullname, about, email`
+	forumColumns  = `id, title, "user", slug, posts, threads, modified`
+	threadColumns = `id, title, author, forum, message, votes, slug, created, modified`
+	postColumns   = `id, parent, author, forum, thread, message, "isEdited", created, modified`
+)
+
+func scanUser(row pgx.Row) (*model.User, error) {
+	u := model.User{}
+	if err := row.Scan(&u.ID, &u.Nickname, &u.Fullname, &u.About, &u.Email); err != nil {
+		return nil, repository.Error(err)
+	}
+	return &u, nil
+}
+
+func scanUsers(rows pgx.Rows, err error) (model.Users, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	users := make(model.Users, 0)
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func scanForum(row pgx.Row) (*model.Forum, error) {
+	f := model.Forum{}
+	if err := row.Scan(&f.ID, &f.Title, &f.User, &f.Slug, &f.Posts, &f.Threads, &f.Modified); err != nil {
+		return nil, repository.Error(err)
+	}
+	return &f, nil
+}
+
+func scanThread(row pgx.Row) (*model.Thread, error) {
+	t := model.Thread{}
+	var created time.Time
+	if err := row.Scan(&t.ID, &t.Title, &t.Author, &t.Forum, &t.Message, &t.Votes, &t.Slug, &created, &t.Modified); err != nil {
+		return nil, repository.Error(err)
+	}
+	t.Created = formatTime(created)
+	return &t, nil
+}
+
+func scanThreads(rows pgx.Rows, err error) (model.Threads, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	threads := make(model.Threads, 0)
+	for rows.Next() {
+		t, err := scanThread(rows)
+		if err != nil {
+			return nil, err
+		}
+		threads = append(threads, t)
+	}
+	return threads, rows.Err()
+}
+
+func scanPost(row pgx.Row) (*model.Post, error) {
+	p := model.Post{}
+	var created time.Time
+	if err := row.Scan(postFields(&p, &created)...); err != nil {
+		return nil, repository.Error(err)
+	}
+	p.Created = formatTime(created)
+	return &p, nil
+}
+
+func postFields(p *model.Post, created *time.Time) []interface{} {
+	return []interface{}{&p.ID, &p.Parent, &p.Author, &p.Forum, &p.Thread, &p.Message, &p.IsEdited, created, &p.Modified}
+}
+
+func scanPosts(rows pgx.Rows, err error) (model.Posts, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	posts := make(model.Posts, 0)
+	for rows.Next() {
+		p, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+	return posts, rows.Err()
+}
+
+func formatTime(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
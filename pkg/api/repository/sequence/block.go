@@ -0,0 +1,69 @@
+package sequence
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"math"
+	"sync"
+)
+
+// BlockAllocator reserves ids from a postgres sequence: each nextval owns [value, value+increment).
+type BlockAllocator struct {
+	db       *pgxpool.Pool
+	sequence string
+
+	mutex     sync.Mutex
+	blockSize int64
+	next      int64
+	end       int64
+}
+
+func NewBlockAllocator(db *pgxpool.Pool, sequence string) *BlockAllocator {
+	return &BlockAllocator{db: db, sequence: sequence}
+}
+
+func (a *BlockAllocator) Next(count int) ([]int, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	result := make([]int, 0, count)
+	for len(result) < count {
+		if a.next == a.end {
+			if err := a.reserve(); err != nil {
+				return nil, err
+			}
+		}
+		result = append(result, int(a.next))
+		a.next++
+	}
+	return result, nil
+}
+
+func (a *BlockAllocator) reserve() error {
+	ctx := context.Background()
+	if a.blockSize == 0 {
+		err := a.db.QueryRow(ctx,
+			`select increment_by from pg_sequences where schemaname = current_schema() and sequencename = $1`,
+			a.sequence,
+		).Scan(&a.blockSize)
+		if err != nil {
+			return fmt.Errorf("sequence %s: %w", a.sequence, err)
+		}
+		if a.blockSize <= 0 {
+			return fmt.Errorf("sequence %s: increment must be positive", a.sequence)
+		}
+	}
+	var start int64
+	if err := a.db.QueryRow(ctx, `select nextval($1)`, a.sequence).Scan(&start); err != nil {
+		return err
+	}
+	if start <= 0 || start > math.MaxInt32 {
+		return ErrExhausted
+	}
+	a.next = start
+	a.end = start + a.blockSize
+	if a.end > math.MaxInt32 {
+		a.end = math.MaxInt32 + 1
+	}
+	return nil
+}
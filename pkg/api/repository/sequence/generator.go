@@ -1,19 +1,34 @@
 package sequence
 
-import "sync/atomic"
+import (
+	"errors"
+	"math"
+	"sync/atomic"
+)
 
+var ErrExhausted = errors.New("sequence exhausted")
+
+type Allocator interface {
+	Next(count int) ([]int, error)
+}
+
+// Generator keeps its counter in memory, so it is only safe for a single process on an empty table.
 type Generator struct {
-	current int32
+	current int64
 }
 
-func NewGenerator() Generator {
-	return Generator{}
+func NewGenerator() *Generator {
+	return &Generator{}
 }
 
-func (s *Generator) Next(count int) []int {
+func (s *Generator) Next(count int) ([]int, error) {
 	result := make([]int, 0, count)
 	for i := 0; i < count; i++ {
-		result = append(result, int(atomic.AddInt32(&s.current, 1)))
+		id := atomic.AddInt64(&s.current, 1)
+		if id > math.MaxInt32 {
+			return nil, ErrExhausted
+		}
+		result = append(result, int(id))
 	}
-	return result
+	return result, nil
 }
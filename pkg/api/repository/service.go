@@ -1,27 +1,39 @@
 package repository
 
+import (
+	"context"
+	"time"
+)
+
 func (r *Repository) CountForums() (count int, err error) {
+	defer observe("CountForums", time.Now())
 	return r.count("forum")
 }
 
 func (r *Repository) CountPosts() (count int, err error) {
+	defer observe("CountPosts", time.Now())
 	return r.count("post")
 }
 
 func (r *Repository) CountThreads() (count int, err error) {
+	defer observe("CountThreads", time.Now())
 	return r.count("thread")
 }
 
 func (r *Repository) CountUsers() (count int, err error) {
+	defer observe("CountUsers", time.Now())
 	return r.count("user")
 }
 
 func (r *Repository) count(table string) (count int, err error) {
-	err = r.db.Get(&count, `select count(*) from "`+table+`"`)
+	err = r.replicas.reader(keyStatus).QueryRow(context.Background(), `select count(*) from "`+table+`"`).Scan(&count)
 	return
 }
 
 func (r *Repository) Clear() error {
-	_, err := r.db.Exec(`truncate thread, post, forum, "user", vote, forum_user`)
+	defer observe("Clear", time.Now())
+	_, err := r.db.Exec(context.Background(), `truncate thread, post, forum, "user", vote, forum_user`)
+	r.caches.FlushAll()
+	r.replicas.wrote(keyGlobal)
 	return err
 }
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	stmtUserByNickname = "userByNickname"
+	stmtUserNickname   = "userNickname"
+	stmtUserNicknames  = "userNicknames"
+	stmtForumBySlug    = "forumBySlug"
+	stmtForumSlug      = "forumSlug"
+	stmtThreadByID     = "threadByID"
+	stmtThreadBySlug   = "threadBySlug"
+	stmtThreadIDByID   = "threadIDByID"
+	stmtThreadIDBySlug = "threadIDBySlug"
+	stmtPostByID       = "postByID"
+	stmtPostParents    = "postParents"
+)
+
+var statements = map[string]string{
+	stmtUserByNickname: `select ` + userColumns + ` from "user" where nickname = $1`,
+	stmtUserNickname:   `select id, nickname from "user" where nickname = $1`,
+	stmtUserNicknames:  `select id, nickname from "user" where nickname = any($1::text[]::citext[])`,
+	stmtForumBySlug:    `select ` + forumColumns + ` from forum where slug = $1`,
+	stmtForumSlug:      `select slug from forum where slug = $1`,
+	stmtThreadByID:     `select ` + threadColumns + ` from thread where id = $1`,
+	stmtThreadBySlug:   `select ` + threadColumns + ` from thread where slug = $1`,
+	stmtThreadIDByID:   `select id, forum from thread where id = $1`,
+	stmtThreadIDBySlug: `select id, forum from thread where slug = $1`,
+	stmtPostByID:       `select ` + postColumns + ` from post where id = $1`,
+	stmtPostParents:    `select id, thread, path from post where id = any($1)`,
+}
+
+// Prepare is meant to be used as the pool AfterConnect hook.
+func Prepare(ctx context.Context, conn *pgx.Conn) error {
+	for name, sql := range statements {
+		if _, err := conn.Prepare(ctx, name, sql); err != nil {
+			return err
+		}
+	}
+	return nil
+}
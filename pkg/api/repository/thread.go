@@ -1,81 +1,96 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 	model2 "github.com/kzon/technopark-sem2-db/pkg/api/model"
+	"github.com/kzon/technopark-sem2-db/pkg/api/repository/cache"
 	"github.com/kzon/technopark-sem2-db/pkg/model"
 	"github.com/kzon/technopark-sem2-db/pkg/repository"
 	"strconv"
+	"time"
 )
 
 func (r *Repository) GetForumThreads(forum string, limit int, desc bool) (model.Threads, error) {
+	defer observe("GetForumThreads", time.Now())
 	query := fmt.Sprintf(
-		"select * from thread where forum = $1 order by created %s limit $2",
+		"select "+threadColumns+" from thread where forum = $1 order by created %s limit $2",
 		r.getOrder(desc),
 	)
-	var threads model.Threads
-	err := r.db.Select(&threads, query, forum, limit)
-	return threads, err
+	return scanThreads(r.replicas.reader(forumKey(forum)).Query(context.Background(), query, forum, limit))
 }
 
 func (r *Repository) GetForumThreadsSince(forum, since string, limit int, desc bool) (model.Threads, error) {
+	defer observe("GetForumThreadsSince", time.Now())
 	createdCond := ">="
 	if desc {
 		createdCond = "<="
 	}
 	query := fmt.Sprintf(
-		"select * from thread where forum = $1 and created %s $2 order by created %s limit $3",
+		"select "+threadColumns+" from thread where forum = $1 and created %s $2 order by created %s limit $3",
 		createdCond, r.getOrder(desc),
 	)
-	threads := make(model.Threads, 0)
-	err := r.db.Select(&threads, query, forum, since, limit)
-	return threads, err
+	return scanThreads(r.replicas.reader(forumKey(forum)).Query(context.Background(), query, forum, since, limit))
 }
 
 func (r *Repository) GetThreadByID(id int) (*model.Thread, error) {
-	return r.getThread("*", "id=$1", id)
+	defer observe("GetThreadByID", time.Now())
+	return scanThread(r.db.QueryRow(context.Background(), stmtThreadByID, id))
 }
 
 func (r *Repository) GetThreadBySlug(slug string) (*model.Thread, error) {
-	return r.getThread("*", "slug=$1", slug)
+	defer observe("GetThreadBySlug", time.Now())
+	return scanThread(r.db.QueryRow(context.Background(), stmtThreadBySlug, slug))
 }
 
 func (r *Repository) GetThreadBySlugOrID(slugOrID string) (*model.Thread, error) {
-	return r.GetThreadFieldsBySlugOrID("*", slugOrID)
-}
-
-func (r *Repository) GetThreadFieldsBySlugOrID(fields, slugOrID string) (*model.Thread, error) {
+	defer observe("GetThreadBySlugOrID", time.Now())
 	id, err := strconv.Atoi(slugOrID)
 	if err != nil {
-		return r.getThread(fields, "slug=$1", slugOrID)
+		return r.GetThreadBySlug(slugOrID)
 	}
-	return r.getThread(fields, "id=$1", id)
+	return r.GetThreadByID(id)
 }
 
-func (r *Repository) getThread(fields, filter string, params ...interface{}) (*model.Thread, error) {
+func (r *Repository) GetThreadIDBySlugOrID(slugOrID string) (*model.Thread, error) {
+	defer observe("GetThreadIDBySlugOrID", time.Now())
+	if identity, err := r.threads.GetBySlugOrID(slugOrID); err == nil {
+		return &model.Thread{ID: identity.ID, Slug: identity.Slug, Forum: identity.Forum}, nil
+	}
+	query, param := stmtThreadIDBySlug, interface{}(slugOrID)
+	if id, err := strconv.Atoi(slugOrID); err == nil {
+		query, param = stmtThreadIDByID, id
+	}
+	generation := r.threads.Generation()
 	t := model.Thread{}
-	err := r.db.Get(&t, "select "+fields+" from thread where "+filter, params...)
-	if err != nil {
+	if err := r.db.QueryRow(context.Background(), query, param).Scan(&t.ID, &t.Slug, &t.Forum); err != nil {
 		return nil, repository.Error(err)
 	}
+	r.threads.AddSince(generation, cache.ThreadIdentity{ID: t.ID, Slug: t.Slug, Forum: t.Forum})
 	return &t, nil
 }
 
 func (r *Repository) CreateThread(forum *model.Forum, thread model2.ThreadCreate) (*model.Thread, error) {
+	defer observe("CreateThread", time.Now())
 	var id int
 	err := r.db.
 		QueryRow(
-			`insert into thread (title, author, forum, message, slug, created) values ($1, $2, $3, $4, $5, $6) returning id`,
+			context.Background(),
+			`insert into thread (title, author, forum, message, slug, created)
+			select $1, nickname, $3, $4, $5, $6 from "user" where nickname = $2 for share
+			returning id, author`,
 			thread.Title, thread.Author, forum.Slug, thread.Message, thread.Slug, thread.Created,
 		).
-		Scan(&id)
+		Scan(&id, &thread.Author)
 	if err != nil {
-		return nil, err
+		return nil, repository.Error(err)
 	}
+	r.replicas.wrote(forumKey(forum.Slug), authorKey(thread.Author), keyStatus)
 	return r.GetThreadByID(id)
 }
 
 func (r *Repository) UpdateThread(threadSlugOrID string, message, title string) (*model.Thread, error) {
+	defer observe("UpdateThread", time.Now())
 	thread, err := r.GetThreadBySlugOrID(threadSlugOrID)
 	if err != nil {
 		return nil, err
@@ -87,8 +102,10 @@ func (r *Repository) UpdateThread(threadSlugOrID string, message, title string)
 		thread.Title = title
 	}
 	_, err = r.db.Exec(
+		context.Background(),
 		`update thread set "message" = $1, title = $2 where id = $3`,
 		thread.Message, thread.Title, thread.ID,
 	)
+	r.replicas.wrote(forumKey(thread.Forum), authorKey(thread.Author))
 	return thread, err
 }
@@ -1,13 +1,15 @@
 package repository
 
 import (
+	"context"
 	"fmt"
 	"github.com/kzon/technopark-sem2-db/pkg/consts"
 	"github.com/kzon/technopark-sem2-db/pkg/model"
-	"strings"
+	"time"
 )
 
 func (r *Repository) GetThreadPosts(thread, limit int, since *int, sort string, desc bool) (model.Posts, error) {
+	defer observe("GetThreadPosts", time.Now())
 	switch sort {
 	case SortFlat, "":
 		return r.getThreadPostsFlat(thread, limit, since, desc)
@@ -35,78 +37,39 @@ func (r *Repository) getThreadPostsFlat(thread, limit int, since *int, desc bool
 		}
 		params = append(params, *since)
 	}
-	return r.getPosts(orderBy, limit, filter, params...)
+	return r.getPosts(r.replicas.reader(threadKey(thread)), orderBy, limit, filter, params...)
 }
 
 func (r *Repository) getThreadPostsTree(thread, limit int, since *int, desc bool) (model.Posts, error) {
-	conditions := []string{"thread = $1"}
-	params := []interface{}{thread}
-	if since != nil {
-		sinceCond, err := r.getSinceCondition(since, desc)
-		if err != nil {
-			return nil, err
-		}
-		conditions = append(conditions, sinceCond)
-	}
-
 	orderBy := []string{"path " + r.getOrder(desc)}
-	filter := strings.Join(conditions, " and ")
-	return r.getPosts(orderBy, limit, filter, params...)
+	db := r.replicas.reader(threadKey(thread))
+	if since == nil {
+		return r.getPosts(db, orderBy, limit, "thread = $1", thread)
+	}
+	filter := "thread = $1 and " + r.getSinceCondition(desc, 2)
+	return r.getPostsSince(db, r.getPostsQuery(orderBy, limit, filter), 2, thread, *since)
 }
 
 func (r *Repository) getThreadPostsParentTree(thread, limit int, since *int, desc bool) (model.Posts, error) {
-	conditions := []string{"parent=0", "thread=$1"}
-
+	rootsFilter := "thread = $1 and parent = 0"
+	params := []interface{}{thread}
 	if since != nil {
-		var operator = ">"
-		if desc {
-			operator = "<"
-		}
-		sincePost, err := r.getPostFields("path", "id=$1", *since)
-		if err != nil {
-			return nil, err
-		}
-		sinceCond := fmt.Sprintf("path %s '%s'", operator, r.getRootPath(sincePost.Path))
-		conditions = append(conditions, sinceCond)
+		rootsFilter += fmt.Sprintf(" and id %s (select path[1] from post where id = $2)", r.getSinceOperator(desc))
+		params = append(params, *since)
 	}
-
-	filter := strings.Join(conditions, " and ")
-	var parents model.Posts
-	err := r.db.Select(&parents, fmt.Sprintf(
-		`select * from post where %s order by id %s limit %d`, filter, r.getOrder(desc), limit),
-		thread,
+	query := fmt.Sprintf(
+		`with roots as (select id as root from post where %s order by id %s %s)
+		select `+postColumns+` from post join roots on post.path[1] = roots.root
+		where post.thread = $1 order by post.path[1] %s, post.path`,
+		rootsFilter, r.getOrder(desc), r.getLimit(limit), r.getOrder(desc),
 	)
-	if err != nil {
-		return nil, err
-	}
-	posts := make(model.Posts, 0)
-	for _, parent := range parents {
-		var childs model.Posts
-		err := r.db.Select(&childs, fmt.Sprintf(
-			`select * from post where substring(path,1,7) = '%s' and parent<>0 order by path`, r.padPostID(parent.ID),
-		))
-		if err != nil {
-			return nil, err
-		}
-		posts = append(posts, parent)
-		posts = append(posts, childs...)
-	}
-	return posts, nil
-}
-
-func (r *Repository) getSinceCondition(since *int, desc bool) (string, error) {
-	var operator = ">"
-	if desc {
-		operator = "<"
-	}
-	sincePost, err := r.getPostFields("path", "id=$1", *since)
-	if err != nil {
-		return "", err
+	db := r.replicas.reader(threadKey(thread))
+	if since == nil {
+		return scanPosts(db.Query(context.Background(), query, params...))
 	}
-	return fmt.Sprintf("path %s '%s'", operator, sincePost.Path), nil
+	return r.getPostsSince(db, query, 2, params...)
 }
 
-func (r *Repository) getRootPath(path string) string {
-	root := strings.Split(path, pathDelim)[0]
-	return root + strings.Repeat(pathDelim+zeroPathStud, maxTreeLevel-1)
+func (r *Repository) getSinceCondition(desc bool, param int) string {
+	return fmt.Sprintf("path %s (select path from post where id = $%d)", r.getSinceOperator(desc), param)
 }
@@ -1,80 +1,149 @@
 package repository
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
+	"github.com/jackc/pgx/v4"
 	"github.com/kzon/technopark-sem2-db/pkg/consts"
 	"github.com/kzon/technopark-sem2-db/pkg/model"
 	"github.com/kzon/technopark-sem2-db/pkg/repository"
+	"strings"
+	"time"
 )
 
+// DeletedNickname is the author of content left by deleted users; no user may take it.
+const DeletedNickname = "[deleted]"
+
 func (r *Repository) GetUserByNickname(nickname string) (*model.User, error) {
-	user := model.User{}
-	err := r.db.Get(&user, `select * from "user" where nickname = $1`, nickname)
-	if err != nil {
-		return nil, repository.Error(err)
-	}
-	return &user, nil
+	defer observe("GetUserByNickname", time.Now())
+	return scanUser(r.db.QueryRow(context.Background(), stmtUserByNickname, nickname))
 }
 
 func (r *Repository) GetUserNickname(nickname string) (string, error) {
+	defer observe("GetUserNickname", time.Now())
 	userNick, err := r.users.GetNickCaseInsensitive(nickname)
 	if err == nil {
 		return userNick, nil
 	}
+	generation := r.users.Generation()
 	user := model.User{}
-	err = r.db.Get(&user, `select id,nickname from "user" where nickname = $1`, nickname)
+	err = r.db.QueryRow(context.Background(), stmtUserNickname, nickname).Scan(&user.ID, &user.Nickname)
 	if err != nil {
 		return "", repository.Error(err)
 	}
-	r.users.Add(user.ID, user.Nickname)
+	r.users.AddSince(generation, user.ID, user.Nickname)
 	return user.Nickname, nil
 }
 
-func (r *Repository) getUserByID(userID int) (*model.User, error) {
-	user := model.User{}
-	err := r.db.Get(&user, `select * from "user" where id = $1`, userID)
-	if err == sql.ErrNoRows {
-		return nil, consts.ErrNotFound
+func (r *Repository) GetUserNicknames(nicknames []string) (map[string]string, error) {
+	defer observe("GetUserNicknames", time.Now())
+	result := make(map[string]string, len(nicknames))
+	missing := make([]string, 0)
+	for _, nickname := range nicknames {
+		if userNick, err := r.users.GetNickCaseInsensitive(nickname); err == nil {
+			result[nickname] = userNick
+		} else {
+			missing = append(missing, nickname)
+		}
+	}
+	if len(missing) == 0 {
+		return result, nil
 	}
-	return &user, err
+	generation := r.users.Generation()
+	rows, err := r.db.Query(context.Background(), stmtUserNicknames, missing)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	found := make(map[string]string, len(missing))
+	for rows.Next() {
+		user := model.User{}
+		if err := rows.Scan(&user.ID, &user.Nickname); err != nil {
+			return nil, err
+		}
+		r.users.AddSince(generation, user.ID, user.Nickname)
+		found[strings.ToLower(user.Nickname)] = user.Nickname
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, nickname := range missing {
+		if userNick, ok := found[strings.ToLower(nickname)]; ok {
+			result[nickname] = userNick
+		}
+	}
+	return result, nil
 }
 
-func (r *Repository) getUserByEmail(email string) (*model.User, error) {
-	user := model.User{}
-	err := r.db.Get(&user, `select * from "user" where email = $1`, email)
+// lockAuthors share-locks the users writing content in tx, so that renames and deletions wait for the content
+// to commit, and returns their nicknames as they are now, keyed by the lower-cased nickname.
+func (r *Repository) lockAuthors(ctx context.Context, tx pgx.Tx, nicknames []string) (map[string]string, error) {
+	rows, err := tx.Query(ctx, `select nickname from "user" where nickname = any($1::text[]::citext[]) for share`, nicknames)
 	if err != nil {
-		return nil, repository.Error(err)
+		return nil, err
 	}
-	return &user, nil
+	defer rows.Close()
+	locked := make(map[string]string, len(nicknames))
+	for rows.Next() {
+		var nickname string
+		if err := rows.Scan(&nickname); err != nil {
+			return nil, err
+		}
+		locked[strings.ToLower(nickname)] = nickname
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, nickname := range nicknames {
+		if _, ok := locked[strings.ToLower(nickname)]; !ok {
+			return nil, fmt.Errorf("%w: user %s", consts.ErrNotFound, nickname)
+		}
+	}
+	return locked, nil
+}
+
+func (r *Repository) getUserByID(userID int) (*model.User, error) {
+	return scanUser(r.db.QueryRow(context.Background(), `select `+userColumns+` from "user" where id = $1`, userID))
+}
+
+func (r *Repository) getUserByEmail(email string) (*model.User, error) {
+	return scanUser(r.db.QueryRow(context.Background(), `select `+userColumns+` from "user" where email = $1`, email))
 }
 
 func (r *Repository) GetUsersByNicknameOrEmail(nickname, email string) ([]*model.User, error) {
-	var users []*model.User
-	err := r.db.Select(&users,
-		`select * from "user" where nickname = $1 or email = $2`,
+	defer observe("GetUsersByNicknameOrEmail", time.Now())
+	users, err := scanUsers(r.db.Query(context.Background(),
+		`select `+userColumns+` from "user" where nickname = $1 or email = $2`,
 		nickname, email,
-	)
+	))
 	if err != nil {
 		return nil, repository.Error(err)
 	}
+	if len(users) == 0 {
+		return nil, nil
+	}
 	return users, nil
 }
 
 func (r *Repository) CreateUser(nickname, email, fullname, about string) (*model.User, error) {
+	defer observe("CreateUser", time.Now())
+	generation := r.users.Generation()
 	var id int
 	err := r.db.QueryRow(
+		context.Background(),
 		`insert into "user" (nickname, email, fullname, about) values ($1, $2, $3, $4) returning id`,
 		nickname, email, fullname, about,
 	).Scan(&id)
 	if err != nil {
 		return nil, err
 	}
-	r.users.Add(id, nickname)
+	r.users.AddSince(generation, id, nickname)
+	r.replicas.wrote(keyStatus)
 	return r.getUserByID(id)
 }
 
 func (r *Repository) UpdateUserByNickname(nickname, email, fullname, about string) error {
+	defer observe("UpdateUserByNickname", time.Now())
 	userByEmail, err := r.getUserByEmail(email)
 	if err != nil && err != consts.ErrNotFound {
 		return err
@@ -83,14 +152,125 @@ func (r *Repository) UpdateUserByNickname(nickname, email, fullname, about strin
 		return fmt.Errorf("%w: user with this email already exists", consts.ErrConflict)
 	}
 	result, err := r.db.Exec(
+		context.Background(),
 		`update "user" set email=$1, fullname=$2, about=$3 where nickname=$4`,
 		email, fullname, about, nickname,
 	)
+	r.users.Invalidate(nickname)
+	r.replicas.wrote(keyUsers)
 	if err != nil {
 		return repository.Error(err)
 	}
-	if affected, _ := result.RowsAffected(); affected == 0 {
+	if result.RowsAffected() == 0 {
 		return consts.ErrNotFound
 	}
 	return nil
 }
+
+// RenameUser changes the nickname together with every copy of it in threads, posts, forums, memberships and votes,
+// updating the rest of the profile in the same transaction.
+func (r *Repository) RenameUser(nickname, newNickname, email, fullname, about string) (*model.User, error) {
+	defer observe("RenameUser", time.Now())
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	user, err := r.renameUser(ctx, tx, nickname, newNickname, email, fullname, about)
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, repository.Error(err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, repository.Error(err)
+	}
+	r.users.Invalidate(nickname)
+	r.replicas.wrote(keyGlobal)
+	return user, nil
+}
+
+func (r *Repository) renameUser(
+	ctx context.Context, tx pgx.Tx, nickname, newNickname, email, fullname, about string,
+) (*model.User, error) {
+	var oldNickname string
+	err := tx.QueryRow(ctx, `select nickname from "user" where nickname = $1 for update`, nickname).Scan(&oldNickname)
+	if err != nil {
+		return nil, err
+	}
+	var emailOwner string
+	err = tx.QueryRow(ctx, `select nickname from "user" where email = $1`, email).Scan(&emailOwner)
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, err
+	}
+	if err == nil && emailOwner != oldNickname {
+		return nil, fmt.Errorf("%w: user with this email already exists", consts.ErrConflict)
+	}
+	user, err := scanUser(tx.QueryRow(
+		ctx, `update "user" set nickname = $1, email = $2, fullname = $3, about = $4 where nickname = $5 returning `+userColumns,
+		newNickname, email, fullname, about, oldNickname,
+	))
+	if err != nil {
+		return nil, err
+	}
+	// the copies are plain text holding the canonical nickname, so they match it exactly
+	for _, query := range []string{
+		`update thread set author = $1 where author = $2`,
+		`update post set author = $1 where author = $2`,
+		`update forum set "user" = $1 where "user" = $2`,
+		`update forum_user set "user" = $1 where "user" = $2`,
+		`update vote set nickname = $1 where nickname = $2`,
+	} {
+		if _, err := tx.Exec(ctx, query, user.Nickname, oldNickname); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// DeleteUser removes the user, attributing their threads, posts and forums to DeletedNickname
+// and withdrawing their votes from the thread totals.
+func (r *Repository) DeleteUser(nickname string) error {
+	defer observe("DeleteUser", time.Now())
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	if err := r.deleteUser(ctx, tx, nickname); err != nil {
+		tx.Rollback(ctx)
+		return repository.Error(err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	r.users.Invalidate(nickname)
+	r.replicas.wrote(keyGlobal)
+	return nil
+}
+
+func (r *Repository) deleteUser(ctx context.Context, tx pgx.Tx, nickname string) error {
+	var id int
+	err := tx.QueryRow(ctx, `select id, nickname from "user" where nickname = $1 for update`, nickname).Scan(&id, &nickname)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `with withdrawn as (delete from vote where nickname = $1 returning thread, voice)
+		update thread set votes = votes - withdrawn.voice from withdrawn where thread.id = withdrawn.thread`, nickname)
+	if err != nil {
+		return err
+	}
+	for _, query := range []string{
+		`update thread set author = $2 where author = $1`,
+		`update post set author = $2 where author = $1`,
+		`update forum set "user" = $2 where "user" = $1`,
+	} {
+		if _, err := tx.Exec(ctx, query, nickname, DeletedNickname); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx, `delete from forum_user where "user" = $1`, nickname); err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `delete from "user" where id = $1`, id)
+	return err
+}
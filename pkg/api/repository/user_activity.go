@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/kzon/technopark-sem2-db/pkg/model"
+	"strings"
+	"time"
+)
+
+// ActivityFilter narrows a user's posts or threads; a zero From or To leaves that end of the range open.
+type ActivityFilter struct {
+	Forum string
+	From  time.Time
+	To    time.Time
+	Limit int
+	Desc  bool
+}
+
+// GetUserPosts pages through the posts of author by (created, id), continuing after the post since.
+func (r *Repository) GetUserPosts(author string, filter ActivityFilter, since *int) (model.Posts, error) {
+	defer observe("GetUserPosts", time.Now())
+	conditions, params := r.getActivityConditions(author, filter)
+	if since != nil {
+		params = append(params, *since)
+		conditions = append(conditions, fmt.Sprintf(
+			"(created, id) %s (select created, id from post where id = $%d)", r.getSinceOperator(filter.Desc), len(params),
+		))
+	}
+	order := r.getOrder(filter.Desc)
+	query := fmt.Sprintf(
+		`select `+postColumns+` from post where %s order by created%s, id%s%s`,
+		strings.Join(conditions, " and "), order, order, r.getLimit(filter.Limit),
+	)
+	db := r.replicas.reader(authorKey(author))
+	if since != nil {
+		return r.getPostsSince(db, query, len(params), params...)
+	}
+	return scanPosts(db.Query(context.Background(), query, params...))
+}
+
+// GetUserThreads pages through the threads of author by creation time, like GetForumThreadsSince.
+func (r *Repository) GetUserThreads(author string, filter ActivityFilter, since string) (model.Threads, error) {
+	defer observe("GetUserThreads", time.Now())
+	conditions, params := r.getActivityConditions(author, filter)
+	if since != "" {
+		operator := ">="
+		if filter.Desc {
+			operator = "<="
+		}
+		params = append(params, since)
+		conditions = append(conditions, fmt.Sprintf("created %s $%d", operator, len(params)))
+	}
+	query := fmt.Sprintf(
+		`select `+threadColumns+` from thread where %s order by created%s, id%s%s`,
+		strings.Join(conditions, " and "), r.getOrder(filter.Desc), r.getOrder(filter.Desc), r.getLimit(filter.Limit),
+	)
+	return scanThreads(r.replicas.reader(authorKey(author)).Query(context.Background(), query, params...))
+}
+
+func (r *Repository) getActivityConditions(author string, filter ActivityFilter) ([]string, []interface{}) {
+	conditions := []string{"author = $1"}
+	params := []interface{}{author}
+	if filter.Forum != "" {
+		params = append(params, filter.Forum)
+		conditions = append(conditions, fmt.Sprintf("forum = $%d", len(params)))
+	}
+	if !filter.From.IsZero() {
+		params = append(params, filter.From)
+		conditions = append(conditions, fmt.Sprintf("created >= $%d", len(params)))
+	}
+	if !filter.To.IsZero() {
+		params = append(params, filter.To)
+		conditions = append(conditions, fmt.Sprintf("created < $%d", len(params)))
+	}
+	return conditions, params
+}
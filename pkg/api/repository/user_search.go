@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/kzon/technopark-sem2-db/pkg/consts"
+	"github.com/kzon/technopark-sem2-db/pkg/model"
+	"strings"
+	"time"
+)
+
+const (
+	MatchPrefix    = "prefix"
+	MatchSubstring = "substring"
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchUsers lists users whose nickname, fullname or email contains query, paginated by nickname.
+// The ilike conditions are served by the trigram indexes on these columns.
+func (r *Repository) SearchUsers(query, match, since string, limit int, desc bool) (model.Users, error) {
+	defer observe("SearchUsers", time.Now())
+	conditions := make([]string, 0, 2)
+	params := make([]interface{}, 0, 2)
+	if query != "" {
+		pattern := likeEscaper.Replace(query) + "%"
+		switch match {
+		case MatchSubstring, "":
+			pattern = "%" + pattern
+		case MatchPrefix:
+		default:
+			return nil, fmt.Errorf("%w: unknown match '%s'", consts.ErrBadRequest, match)
+		}
+		params = append(params, pattern)
+		conditions = append(conditions, fmt.Sprintf(
+			"(nickname::text ilike $%[1]d or fullname ilike $%[1]d or email::text ilike $%[1]d)", len(params),
+		))
+	}
+	if since != "" {
+		params = append(params, since)
+		conditions = append(conditions, fmt.Sprintf("nickname %s $%d", r.getSinceOperator(desc), len(params)))
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "where " + strings.Join(conditions, " and ")
+	}
+	sql := fmt.Sprintf(
+		`select `+userColumns+` from "user" %s order by nickname%s%s`,
+		where, r.getOrder(desc), r.getLimit(limit),
+	)
+	return scanUsers(r.replicas.reader(keyUsers, keyStatus).Query(context.Background(), sql, params...))
+}
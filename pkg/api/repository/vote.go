@@ -1,45 +1,70 @@
 package repository
 
 import (
-	"database/sql"
+	"context"
+	"github.com/jackc/pgx/v4"
 	"github.com/kzon/technopark-sem2-db/pkg/model"
+	"strings"
+	"time"
 )
 
 func (r *Repository) AddThreadVote(thread *model.Thread, nickname string, voice int) (newVotes int, err error) {
-	oldVoice, err := r.getVoice(nickname, thread.ID)
+	defer observe("AddThreadVote", time.Now())
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return
 	}
-	if oldVoice == voice {
-		return thread.Votes, nil
-	}
-	newVoice := voice - oldVoice
-	tx, err := r.db.Beginx()
-	if err != nil {
-		return
-	}
-	err = tx.Get(&newVotes, `update thread set votes = votes + $1 where id = $2 returning votes`, newVoice, thread.ID)
-	if err != nil {
-		tx.Rollback()
-		return
-	}
-	if _, err = tx.Exec(`delete from vote where thread = $1 and nickname = $2`, thread.ID, nickname); err != nil {
-		tx.Rollback()
+	if newVotes, err = r.addThreadVote(ctx, tx, thread.ID, nickname, voice); err != nil {
+		tx.Rollback(ctx)
 		return
 	}
-	if _, err = tx.Exec(`insert into vote (thread, nickname, voice) values ($1, $2, $3)`, thread.ID, nickname, voice); err != nil {
-		tx.Rollback()
+	if err = tx.Commit(ctx); err != nil {
 		return
 	}
-	err = tx.Commit()
+	r.replicas.wrote(forumKey(thread.Forum), authorKey(nickname))
 	return
 }
 
-func (r *Repository) getVoice(nickname string, threadID int) (int, error) {
-	var voice int
-	err := r.db.Get(&voice, `select voice from vote where nickname = $1 and thread = $2`, nickname, threadID)
-	if err == sql.ErrNoRows {
-		return 0, nil
+// addThreadVote stores the voice and applies its difference from the previous one to the thread total.
+// The unique (thread, nickname) key makes concurrent first votes of one user wait for each other,
+// and the row lock serializes later changes, so every difference is counted exactly once.
+func (r *Repository) addThreadVote(ctx context.Context, tx pgx.Tx, threadID int, nickname string, voice int) (int, error) {
+	nicknames, err := r.lockAuthors(ctx, tx, []string{nickname})
+	if err != nil {
+		return 0, err
+	}
+	nickname = nicknames[strings.ToLower(nickname)]
+	delta := voice
+	inserted, err := tx.Exec(
+		ctx,
+		`insert into vote (thread, nickname, voice) values ($1, $2, $3) on conflict (thread, nickname) do nothing`,
+		threadID, nickname, voice,
+	)
+	if err != nil {
+		return 0, err
+	}
+	if inserted.RowsAffected() == 0 {
+		var oldVoice int
+		err := tx.QueryRow(
+			ctx, `select voice from vote where thread = $1 and nickname = $2 for update`, threadID, nickname,
+		).Scan(&oldVoice)
+		if err != nil {
+			return 0, err
+		}
+		delta = voice - oldVoice
+		if delta != 0 {
+			_, err := tx.Exec(ctx, `update vote set voice = $1 where thread = $2 and nickname = $3`, voice, threadID, nickname)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	var votes int
+	if delta == 0 {
+		err = tx.QueryRow(ctx, `select votes from thread where id = $1`, threadID).Scan(&votes)
+	} else {
+		err = tx.QueryRow(ctx, `update thread set votes = votes + $1 where id = $2 returning votes`, delta, threadID).Scan(&votes)
 	}
-	return voice, err
+	return votes, err
 }
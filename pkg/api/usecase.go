@@ -4,24 +4,46 @@ import (
 	"fmt"
 	apiModel "github.com/kzon/technopark-sem2-db/pkg/api/model"
 	"github.com/kzon/technopark-sem2-db/pkg/api/repository"
+	"github.com/kzon/technopark-sem2-db/pkg/api/repository/cache"
+	"github.com/kzon/technopark-sem2-db/pkg/coalesce"
 	"github.com/kzon/technopark-sem2-db/pkg/consts"
 	"github.com/kzon/technopark-sem2-db/pkg/model"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type Usecase struct {
-	repo repository.Repository
+	repo    repository.Repository
+	users   *coalesce.Group
+	forums  *coalesce.Group
+	threads *coalesce.Group
 }
 
 func NewUsecase(repo repository.Repository) Usecase {
-	return Usecase{repo: repo}
+	return Usecase{
+		repo:    repo,
+		users:   coalesce.NewGroup("user"),
+		forums:  coalesce.NewGroup("forum"),
+		threads: coalesce.NewGroup("thread"),
+	}
 }
 
 func (u *Usecase) getUserByNickname(nickname string) (*model.User, error) {
-	return u.repo.GetUserByNickname(nickname)
+	v, err, _ := u.users.Do(strings.ToLower(nickname), func() (interface{}, error) {
+		return u.repo.GetUserByNickname(nickname)
+	})
+	if err != nil {
+		return nil, err
+	}
+	user := *v.(*model.User)
+	return &user, nil
 }
 
-func (u *Usecase) createUser(nickname, email, fullname, about string) ([]*model.User, error) {
+func (u *Usecase) createUser(nickname, email, fullname, about string) (model.Users, error) {
+	if err := checkNicknameAllowed(nickname); err != nil {
+		return nil, err
+	}
 	existing, err := u.repo.GetUsersByNicknameOrEmail(nickname, email)
 	if err != nil && err != consts.ErrNotFound {
 		return nil, err
@@ -30,29 +52,128 @@ func (u *Usecase) createUser(nickname, email, fullname, about string) ([]*model.
 		return existing, consts.ErrConflict
 	}
 	user, err := u.repo.CreateUser(nickname, email, fullname, about)
-	return []*model.User{user}, err
+	u.users.Forget(strings.ToLower(nickname))
+	return model.Users{user}, err
 }
 
-func (u *Usecase) updateUser(nickname, email, fullname, about string) (*model.User, error) {
+func (u *Usecase) updateUser(nickname string, input apiModel.UserInput) (*model.User, error) {
 	userToUpdate, err := u.repo.GetUserByNickname(nickname)
 	if err != nil {
 		return nil, err
 	}
-	if email == "" {
-		email = userToUpdate.Email
+	rename := input.Nickname != "" && input.Nickname != userToUpdate.Nickname
+	if rename {
+		if err := checkNicknameAllowed(input.Nickname); err != nil {
+			return nil, err
+		}
 	}
-	if fullname == "" {
-		fullname = userToUpdate.Fullname
+	if rename && !strings.EqualFold(input.Nickname, userToUpdate.Nickname) {
+		existing, err := u.repo.GetUserNickname(input.Nickname)
+		if err != nil && err != consts.ErrNotFound {
+			return nil, err
+		}
+		if err == nil {
+			return nil, fmt.Errorf("%w: user with nickname %s already exists", consts.ErrConflict, existing)
+		}
 	}
-	if about == "" {
-		about = userToUpdate.About
+	if input.Email == "" {
+		input.Email = userToUpdate.Email
 	}
-	if err := u.repo.UpdateUserByNickname(nickname, email, fullname, about); err != nil {
+	if input.Fullname == "" {
+		input.Fullname = userToUpdate.Fullname
+	}
+	if input.About == "" {
+		input.About = userToUpdate.About
+	}
+	if rename {
+		user, err := u.repo.RenameUser(userToUpdate.Nickname, input.Nickname, input.Email, input.Fullname, input.About)
+		u.forgetAll()
+		return user, err
+	}
+	err = u.repo.UpdateUserByNickname(nickname, input.Email, input.Fullname, input.About)
+	u.users.Forget(strings.ToLower(nickname))
+	if err != nil {
 		return nil, err
 	}
 	return u.repo.GetUserByNickname(nickname)
 }
 
+func (u *Usecase) deleteUser(nickname string) error {
+	err := u.repo.DeleteUser(nickname)
+	u.forgetAll()
+	return err
+}
+
+func (u *Usecase) exportUser(nickname string) (*repository.UserExport, error) {
+	return u.repo.BeginUserExport(nickname)
+}
+
+func (u *Usecase) getUserPosts(
+	nickname, forum, from, to string, since *int, limit int, desc bool,
+) (model.Posts, error) {
+	author, filter, err := u.getActivityFilter(nickname, forum, from, to, limit, desc)
+	if err != nil {
+		return nil, err
+	}
+	return u.repo.GetUserPosts(author, filter, since)
+}
+
+func (u *Usecase) getUserThreads(
+	nickname, forum, from, to, since string, limit int, desc bool,
+) (model.Threads, error) {
+	author, filter, err := u.getActivityFilter(nickname, forum, from, to, limit, desc)
+	if err != nil {
+		return nil, err
+	}
+	return u.repo.GetUserThreads(author, filter, since)
+}
+
+func (u *Usecase) getActivityFilter(
+	nickname, forum, from, to string, limit int, desc bool,
+) (string, repository.ActivityFilter, error) {
+	filter := repository.ActivityFilter{Limit: limit, Desc: desc}
+	author, err := u.repo.GetUserNickname(nickname)
+	if err != nil {
+		return "", filter, err
+	}
+	if forum != "" {
+		f, err := u.repo.GetForumSlug(forum)
+		if err != nil {
+			return "", filter, err
+		}
+		filter.Forum = f.Slug
+	}
+	if filter.From, err = parseActivityTime("from", from); err != nil {
+		return "", filter, err
+	}
+	if filter.To, err = parseActivityTime("to", to); err != nil {
+		return "", filter, err
+	}
+	return author, filter, nil
+}
+
+func parseActivityTime(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s must be an RFC 3339 time", consts.ErrBadRequest, name)
+	}
+	return t, nil
+}
+
+func checkNicknameAllowed(nickname string) error {
+	if strings.EqualFold(nickname, repository.DeletedNickname) {
+		return fmt.Errorf("%w: nickname %s is reserved", consts.ErrBadRequest, nickname)
+	}
+	return nil
+}
+
+func (u *Usecase) searchUsers(query, match, since string, limit int, desc bool) (model.Users, error) {
+	return u.repo.SearchUsers(query, match, since, limit, desc)
+}
+
 func (u *Usecase) createForum(title, slug, nickname string) (*model.Forum, error) {
 	userNick, err := u.repo.GetUserNickname(nickname)
 	if err != nil {
@@ -67,13 +188,17 @@ func (u *Usecase) createForum(title, slug, nickname string) (*model.Forum, error
 		return existingForum, fmt.Errorf("%w: forum with this slug already exists", consts.ErrConflict)
 	}
 
-	return u.repo.CreateForum(title, slug, userNick)
+	forum, err := u.repo.CreateForum(title, slug, userNick)
+	u.forums.Forget(strings.ToLower(slug))
+	return forum, err
 }
 
 func (u *Usecase) createThread(forumSlug string, thread apiModel.ThreadCreate) (*model.Thread, error) {
-	if _, err := u.repo.GetUserNickname(thread.Author); err != nil {
+	userNick, err := u.repo.GetUserNickname(thread.Author)
+	if err != nil {
 		return nil, err
 	}
+	thread.Author = userNick
 	forum, err := u.repo.GetForumSlug(forumSlug)
 	if err != nil {
 		return nil, err
@@ -93,45 +218,76 @@ func (u *Usecase) createThread(forumSlug string, thread apiModel.ThreadCreate) (
 		thread.Created = time.Now().Format(time.RFC3339)
 	}
 
-	return u.repo.CreateThread(forum, thread)
+	created, err := u.repo.CreateThread(forum, thread)
+	u.forums.Forget(strings.ToLower(forum.Slug))
+	if err != nil {
+		return nil, err
+	}
+	u.forgetThread(created)
+	return created, nil
 }
 
 func (u *Usecase) updateThread(threadSlugOrID string, message, title string) (*model.Thread, error) {
-	return u.repo.UpdateThread(threadSlugOrID, message, title)
+	thread, err := u.repo.UpdateThread(threadSlugOrID, message, title)
+	u.threads.Forget(strings.ToLower(threadSlugOrID))
+	if err != nil {
+		return nil, err
+	}
+	u.forgetThread(thread)
+	return thread, nil
 }
 
 func (u *Usecase) createPosts(threadSlugOrID string, posts []*apiModel.PostCreate) (model.Posts, error) {
-	thread, err := u.repo.GetThreadFieldsBySlugOrID("id, forum", threadSlugOrID)
+	thread, err := u.repo.GetThreadIDBySlugOrID(threadSlugOrID)
 	if err != nil {
 		return nil, err
 	}
-	if err := u.checkPostsCreate(posts, thread.ID); err != nil {
+	parents, err := u.checkPostsCreate(posts, thread.ID)
+	if err != nil {
 		return nil, err
 	}
-	return u.repo.CreatePosts(posts, thread)
+	created, err := u.repo.CreatePosts(posts, thread, parents)
+	u.forums.Forget(strings.ToLower(thread.Forum))
+	return created, err
 }
 
-func (u *Usecase) checkPostsCreate(posts []*apiModel.PostCreate, threadID int) error {
+func (u *Usecase) checkPostsCreate(posts []*apiModel.PostCreate, threadID int) (map[int]*model.Post, error) {
+	authors := make([]string, 0, len(posts))
+	parentIDs := make([]int, 0, len(posts))
 	for _, post := range posts {
-		if err := u.checkPostCreate(post, threadID); err != nil {
-			return err
+		authors = append(authors, post.Author)
+		if post.Parent != 0 {
+			parentIDs = append(parentIDs, post.Parent)
 		}
 	}
-	return nil
+	nicknames, err := u.repo.GetUserNicknames(authors)
+	if err != nil {
+		return nil, err
+	}
+	parents, err := u.repo.GetPostParents(parentIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, post := range posts {
+		if err := u.checkPostCreate(post, threadID, nicknames, parents); err != nil {
+			return nil, err
+		}
+		post.Author = nicknames[post.Author]
+	}
+	return parents, nil
 }
 
-func (u *Usecase) checkPostCreate(post *apiModel.PostCreate, threadID int) error {
-	if _, err := u.repo.GetUserNickname(post.Author); err != nil {
-		return err
+func (u *Usecase) checkPostCreate(
+	post *apiModel.PostCreate, threadID int, nicknames map[string]string, parents map[int]*model.Post,
+) error {
+	if _, ok := nicknames[post.Author]; !ok {
+		return consts.ErrNotFound
 	}
 	if post.Parent != 0 {
-		parent, err := u.repo.GetPostByID(post.Parent)
-		if err == consts.ErrNotFound {
+		parent, ok := parents[post.Parent]
+		if !ok {
 			return fmt.Errorf("%w: post parent do not exists", consts.ErrConflict)
 		}
-		if err != nil {
-			return err
-		}
 		if parent.Thread != threadID {
 			return fmt.Errorf("%w: parent post was created in another thread", consts.ErrConflict)
 		}
@@ -140,7 +296,14 @@ func (u *Usecase) checkPostCreate(post *apiModel.PostCreate, threadID int) error
 }
 
 func (u *Usecase) getForum(slug string) (*model.Forum, error) {
-	return u.repo.GetForumBySlug(slug)
+	v, err, _ := u.forums.Do(strings.ToLower(slug), func() (interface{}, error) {
+		return u.repo.GetForumBySlug(slug)
+	})
+	if err != nil {
+		return nil, err
+	}
+	forum := *v.(*model.Forum)
+	return &forum, nil
 }
 
 func (u *Usecase) getForumThreads(forumSlug, since string, limit int, desc bool) (model.Threads, error) {
@@ -165,6 +328,9 @@ func (u *Usecase) getForumUsers(forum, since string, limit int, desc bool) (mode
 }
 
 func (u *Usecase) voteForThread(threadSlugOrID string, vote apiModel.Vote) (*model.Thread, error) {
+	if vote.Voice != 1 && vote.Voice != -1 {
+		return nil, fmt.Errorf("%w: voice must be -1 or 1", consts.ErrBadRequest)
+	}
 	thread, err := u.repo.GetThreadBySlugOrID(threadSlugOrID)
 	if err != nil {
 		return nil, err
@@ -174,43 +340,59 @@ func (u *Usecase) voteForThread(threadSlugOrID string, vote apiModel.Vote) (*mod
 		return nil, err
 	}
 	newVotes, err := u.repo.AddThreadVote(thread, userNick, vote.Voice)
+	u.forgetThread(thread)
 	thread.Votes = newVotes
 	return thread, err
 }
 
+// forgetThread makes the next getThread of a thread just written read it anew, by id or slug.
+func (u *Usecase) forgetThread(thread *model.Thread) {
+	u.threads.Forget(strconv.Itoa(thread.ID), strings.ToLower(thread.Slug))
+}
+
+func (u *Usecase) forgetAll() {
+	u.users.ForgetAll()
+	u.forums.ForgetAll()
+	u.threads.ForgetAll()
+}
+
 func (u *Usecase) getThread(threadSlugOrID string) (*model.Thread, error) {
-	return u.repo.GetThreadBySlugOrID(threadSlugOrID)
+	v, err, _ := u.threads.Do(strings.ToLower(threadSlugOrID), func() (interface{}, error) {
+		return u.repo.GetThreadBySlugOrID(threadSlugOrID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	thread := *v.(*model.Thread)
+	return &thread, nil
 }
 
 func (u *Usecase) getThreadPosts(threadSlugOrID string, limit int, since *int, sort string, desc bool) (model.Posts, error) {
-	thread, err := u.repo.GetThreadFieldsBySlugOrID("id", threadSlugOrID)
+	thread, err := u.repo.GetThreadIDBySlugOrID(threadSlugOrID)
 	if err != nil {
 		return nil, err
 	}
 	return u.repo.GetThreadPosts(thread.ID, limit, since, sort, desc)
 }
 
-type postDetails struct {
-	Post   *model.Post
-	Author *model.User
-	Forum  *model.Forum
-	Thread *model.Thread
-}
-
-func (u *Usecase) getPostDetails(id int, related []string) (*postDetails, error) {
+func (u *Usecase) getPostDetails(id int, related []string) (*apiModel.PostDetails, error) {
 	post, err := u.repo.GetPostByID(id)
 	if err != nil {
 		return nil, err
 	}
-	details := postDetails{Post: post}
+	details := apiModel.PostDetails{Post: post}
 	for _, r := range related {
 		switch r {
 		case "user":
-			details.Author, err = u.repo.GetUserByNickname(post.Author)
+			if post.Author == repository.DeletedNickname {
+				details.Author = &model.User{Nickname: repository.DeletedNickname}
+				continue
+			}
+			details.Author, err = u.getUserByNickname(post.Author)
 		case "forum":
-			details.Forum, err = u.repo.GetForumBySlug(post.Forum)
+			details.Forum, err = u.getForum(post.Forum)
 		case "thread":
-			details.Thread, err = u.repo.GetThreadByID(post.Thread)
+			details.Thread, err = u.getThread(strconv.Itoa(post.Thread))
 		}
 		if err != nil {
 			return nil, err
@@ -249,6 +431,58 @@ func (u *Usecase) getStatus() (s apiModel.Status, err error) {
 	return
 }
 
+func (u *Usecase) getPoolStat() apiModel.PoolStat {
+	return u.repo.PoolStat()
+}
+
+func (u *Usecase) getCacheStats() map[string]cache.Stats {
+	return u.repo.CacheStats()
+}
+
+func (u *Usecase) flushCache(name string) error {
+	if name == "" {
+		u.repo.FlushCaches()
+		return nil
+	}
+	return u.repo.FlushCache(name)
+}
+
+func (u *Usecase) ingest(records []*apiModel.IngestRecord) (apiModel.IngestResult, error) {
+	batch := repository.IngestBatch{}
+	for i, record := range records {
+		switch {
+		case record.User != nil:
+			if err := checkNicknameAllowed(record.User.Nickname); err != nil {
+				return apiModel.IngestResult{}, err
+			}
+			batch.Users = append(batch.Users, record.User)
+		case record.Forum != nil:
+			batch.Forums = append(batch.Forums, &model.Forum{
+				Slug:  record.Forum.Slug,
+				Title: record.Forum.Title,
+				User:  record.Forum.User,
+			})
+		case record.Thread != nil:
+			batch.Threads = append(batch.Threads, record.Thread)
+		case record.Post != nil:
+			batch.Posts = append(batch.Posts, record.Post)
+		default:
+			return apiModel.IngestResult{}, fmt.Errorf("%w: record %d has no user, forum, thread or post", consts.ErrBadRequest, i+1)
+		}
+	}
+	result := apiModel.IngestResult{
+		Users:   len(batch.Users),
+		Forums:  len(batch.Forums),
+		Threads: len(batch.Threads),
+		Posts:   len(batch.Posts),
+	}
+	err := u.repo.Ingest(&batch)
+	u.forgetAll()
+	return result, err
+}
+
 func (u *Usecase) clear() error {
-	return u.repo.Clear()
+	err := u.repo.Clear()
+	u.forgetAll()
+	return err
 }
@@ -0,0 +1,78 @@
+package coalesce
+
+import (
+	"github.com/kzon/technopark-sem2-db/pkg/metrics"
+	"sync"
+)
+
+var (
+	callsTotal = metrics.NewCounterVec(
+		"coalesce_calls_total", "Lookups passed through a coalescing group.", "group",
+	)
+	sharedTotal = metrics.NewCounterVec(
+		"coalesce_shared_total", "Lookups answered by an identical lookup already in flight.", "group",
+	)
+)
+
+func init() {
+	metrics.Register(callsTotal, sharedTotal)
+}
+
+type call struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// Group runs at most one function per key at a time; concurrent callers with the same key share its result.
+type Group struct {
+	name  string
+	mutex sync.Mutex
+	calls map[string]*call
+}
+
+func NewGroup(name string) *Group {
+	return &Group{name: name, calls: make(map[string]*call)}
+}
+
+// Do returns the result of fn, and whether it was shared with another caller.
+func (g *Group) Do(key string, fn func() (interface{}, error)) (interface{}, error, bool) {
+	callsTotal.Inc(g.name)
+	g.mutex.Lock()
+	if c, ok := g.calls[key]; ok {
+		g.mutex.Unlock()
+		sharedTotal.Inc(g.name)
+		<-c.done
+		return c.value, c.err, true
+	}
+	c := &call{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mutex.Unlock()
+
+	defer func() {
+		g.mutex.Lock()
+		if g.calls[key] == c {
+			delete(g.calls, key)
+		}
+		g.mutex.Unlock()
+		close(c.done)
+	}()
+	c.value, c.err = fn()
+	return c.value, c.err, false
+}
+
+// Forget detaches the calls in flight for keys, so that callers arriving after a write start a new call
+// instead of sharing one that may have read the data before the write.
+func (g *Group) Forget(keys ...string) {
+	g.mutex.Lock()
+	for _, key := range keys {
+		delete(g.calls, key)
+	}
+	g.mutex.Unlock()
+}
+
+func (g *Group) ForgetAll() {
+	g.mutex.Lock()
+	g.calls = make(map[string]*call)
+	g.mutex.Unlock()
+}
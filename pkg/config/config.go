@@ -0,0 +1,298 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type (
+	Config struct {
+		Server     Server     `json:"server"`
+		DB         DB         `json:"db"`
+		Repository Repository `json:"repository"`
+	}
+
+	Server struct {
+		Listen             string   `json:"listen"`
+		Concurrency        int      `json:"concurrency"`
+		ReadTimeout        Duration `json:"read_timeout"`
+		WriteTimeout       Duration `json:"write_timeout"`
+		IdleTimeout        Duration `json:"idle_timeout"`
+		MaxRequestBodySize int      `json:"max_request_body_size"`
+		ShutdownTimeout    Duration `json:"shutdown_timeout"`
+	}
+
+	DB struct {
+		DSN               string     `json:"dsn"`
+		ReplicaDSNs       StringList `json:"replica_dsns"`
+		MaxConns          int        `json:"max_conns"`
+		MaxConnLifetime   Duration   `json:"max_conn_lifetime"`
+		HealthCheckPeriod Duration   `json:"health_check_period"`
+	}
+
+	Repository struct {
+		PostChunkSize int      `json:"post_chunk_size"`
+		UserCacheSize int      `json:"user_cache_size"`
+		UserCacheTTL  Duration `json:"user_cache_ttl"`
+
+		ForumCacheSize   int      `json:"forum_cache_size"`
+		ThreadCacheSize  int      `json:"thread_cache_size"`
+		IdentityCacheTTL Duration `json:"identity_cache_ttl"`
+
+		ReplicaMaxLag         Duration `json:"replica_max_lag"`
+		ReplicaLagCheckPeriod Duration `json:"replica_lag_check_period"`
+		ReadStickiness        Duration `json:"read_stickiness"`
+
+		MaxExports int `json:"max_exports"`
+	}
+)
+
+// maxPostChunkSize keeps a multi-row post insert under the postgres limit of 65535 bind parameters.
+const maxPostChunkSize = 65535 / 8
+
+var envNames = map[string]string{
+	"listen":                     "HTTP_LISTEN",
+	"concurrency":                "HTTP_CONCURRENCY",
+	"read-timeout":               "HTTP_READ_TIMEOUT",
+	"write-timeout":              "HTTP_WRITE_TIMEOUT",
+	"idle-timeout":               "HTTP_IDLE_TIMEOUT",
+	"max-request-body-size":      "HTTP_MAX_REQUEST_BODY_SIZE",
+	"shutdown-timeout":           "HTTP_SHUTDOWN_TIMEOUT",
+	"dsn":                        "POSTGRES_DSN",
+	"replica-dsns":               "POSTGRES_REPLICA_DSNS",
+	"db-max-conns":               "DB_MAX_CONNS",
+	"db-max-conn-lifetime":       "DB_MAX_CONN_LIFETIME",
+	"db-health-check-period":     "DB_HEALTH_CHECK_PERIOD",
+	"repository-post-chunk-size": "REPOSITORY_POST_CHUNK_SIZE",
+	"user-cache-size":            "USER_CACHE_SIZE",
+	"user-cache-ttl":             "USER_CACHE_TTL",
+	"forum-cache-size":           "FORUM_CACHE_SIZE",
+	"thread-cache-size":          "THREAD_CACHE_SIZE",
+	"identity-cache-ttl":         "IDENTITY_CACHE_TTL",
+	"replica-max-lag":            "REPLICA_MAX_LAG",
+	"replica-lag-check-period":   "REPLICA_LAG_CHECK_PERIOD",
+	"read-stickiness":            "READ_STICKINESS",
+	"max-exports":                "MAX_EXPORTS",
+}
+
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Listen:             ":5000",
+			Concurrency:        256 * 1024,
+			ReadTimeout:        Duration{10 * time.Second},
+			WriteTimeout:       Duration{10 * time.Second},
+			IdleTimeout:        Duration{time.Minute},
+			MaxRequestBodySize: 4 * 1024 * 1024,
+			ShutdownTimeout:    Duration{10 * time.Second},
+		},
+		DB: DB{
+			MaxConns:          8,
+			MaxConnLifetime:   Duration{time.Hour},
+			HealthCheckPeriod: Duration{time.Minute},
+		},
+		Repository: Repository{
+			PostChunkSize: 50,
+			UserCacheSize: 100000,
+
+			ForumCacheSize:  10000,
+			ThreadCacheSize: 100000,
+
+			ReplicaMaxLag:         Duration{time.Second},
+			ReplicaLagCheckPeriod: Duration{500 * time.Millisecond},
+			ReadStickiness:        Duration{2 * time.Second},
+
+			MaxExports: 2,
+		},
+	}
+}
+
+// Load builds the configuration from defaults, a JSON file, environment variables and flags, later sources winning.
+func Load(args []string) (*Config, error) {
+	cli := Default()
+	flags := cli.flagSet()
+	path := flags.String("config", os.Getenv("CONFIG_FILE"), "path to a JSON config file")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	c := Default()
+	if *path != "" {
+		if err := c.loadFile(*path); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.loadEnv(); err != nil {
+		return nil, err
+	}
+	effective := c.flagSet()
+	var err error
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name != "config" && err == nil {
+			err = effective.Set(f.Name, f.Value.String())
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, c.Validate()
+}
+
+func (c *Config) flagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("server", flag.ContinueOnError)
+	f.StringVar(&c.Server.Listen, "listen", c.Server.Listen, "HTTP listen address")
+	f.IntVar(&c.Server.Concurrency, "concurrency", c.Server.Concurrency, "maximum number of concurrent HTTP connections")
+	f.DurationVar(&c.Server.ReadTimeout.Duration, "read-timeout", c.Server.ReadTimeout.Duration, "HTTP request read timeout")
+	f.DurationVar(&c.Server.WriteTimeout.Duration, "write-timeout", c.Server.WriteTimeout.Duration, "HTTP response write timeout")
+	f.DurationVar(&c.Server.IdleTimeout.Duration, "idle-timeout", c.Server.IdleTimeout.Duration, "HTTP keep-alive idle timeout")
+	f.IntVar(&c.Server.MaxRequestBodySize, "max-request-body-size", c.Server.MaxRequestBodySize, "maximum HTTP request body size in bytes")
+	f.DurationVar(&c.Server.ShutdownTimeout.Duration, "shutdown-timeout", c.Server.ShutdownTimeout.Duration, "time each component, such as the HTTP server draining requests, gets to stop on shutdown")
+	f.StringVar(&c.DB.DSN, "dsn", c.DB.DSN, "postgres connection string")
+	f.Var(&c.DB.ReplicaDSNs, "replica-dsns", "comma-separated postgres replica connection strings for reads")
+	f.IntVar(&c.DB.MaxConns, "db-max-conns", c.DB.MaxConns, "maximum postgres pool size")
+	f.DurationVar(&c.DB.MaxConnLifetime.Duration, "db-max-conn-lifetime", c.DB.MaxConnLifetime.Duration, "postgres connection lifetime")
+	f.DurationVar(&c.DB.HealthCheckPeriod.Duration, "db-health-check-period", c.DB.HealthCheckPeriod.Duration, "postgres idle connection health check period")
+	f.IntVar(&c.Repository.PostChunkSize, "repository-post-chunk-size", c.Repository.PostChunkSize, "posts per insert statement")
+	f.IntVar(&c.Repository.UserCacheSize, "user-cache-size", c.Repository.UserCacheSize, "maximum number of cached users")
+	f.DurationVar(&c.Repository.UserCacheTTL.Duration, "user-cache-ttl", c.Repository.UserCacheTTL.Duration, "user cache entry lifetime, 0 keeps entries until evicted")
+	f.IntVar(&c.Repository.ForumCacheSize, "forum-cache-size", c.Repository.ForumCacheSize, "maximum number of cached forum slugs")
+	f.IntVar(&c.Repository.ThreadCacheSize, "thread-cache-size", c.Repository.ThreadCacheSize, "maximum number of cached thread identities")
+	f.DurationVar(&c.Repository.IdentityCacheTTL.Duration, "identity-cache-ttl", c.Repository.IdentityCacheTTL.Duration, "forum and thread cache entry lifetime, 0 keeps entries until evicted")
+	f.DurationVar(&c.Repository.ReplicaMaxLag.Duration, "replica-max-lag", c.Repository.ReplicaMaxLag.Duration, "replay lag above which reads fall back to the primary")
+	f.DurationVar(&c.Repository.ReplicaLagCheckPeriod.Duration, "replica-lag-check-period", c.Repository.ReplicaLagCheckPeriod.Duration, "how often replica lag is measured")
+	f.DurationVar(&c.Repository.ReadStickiness.Duration, "read-stickiness", c.Repository.ReadStickiness.Duration, "how long reads of data written through this instance stay on the primary")
+	f.IntVar(&c.Repository.MaxExports, "max-exports", c.Repository.MaxExports, "maximum number of user exports streamed at once, each holding a postgres connection")
+	return f
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	flags := c.flagSet()
+	for name, env := range envNames {
+		value, ok := os.LookupEnv(env)
+		if !ok {
+			continue
+		}
+		if err := flags.Set(name, value); err != nil {
+			return fmt.Errorf("%s: %w", env, err)
+		}
+	}
+	return nil
+}
+
+func (c *Config) Validate() error {
+	switch {
+	case c.Server.Listen == "":
+		return errors.New("config: listen address is empty")
+	case c.Server.Concurrency <= 0:
+		return errors.New("config: concurrency must be positive")
+	case c.Server.ReadTimeout.Duration < 0 || c.Server.WriteTimeout.Duration < 0 || c.Server.IdleTimeout.Duration < 0:
+		return errors.New("config: timeouts must not be negative")
+	case c.Server.ShutdownTimeout.Duration <= 0:
+		return errors.New("config: shutdown timeout must be positive")
+	case c.Server.MaxRequestBodySize <= 0:
+		return errors.New("config: max request body size must be positive")
+	case c.DB.DSN == "":
+		return errors.New("config: postgres dsn is empty")
+	case c.DB.MaxConns <= 0:
+		return errors.New("config: db max conns must be positive")
+	case c.DB.MaxConnLifetime.Duration <= 0 || c.DB.HealthCheckPeriod.Duration <= 0:
+		return errors.New("config: db durations must be positive")
+	case c.Repository.PostChunkSize <= 0 || c.Repository.PostChunkSize > maxPostChunkSize:
+		return fmt.Errorf("config: post chunk size must be between 1 and %d", maxPostChunkSize)
+	case c.Repository.UserCacheSize <= 0:
+		return errors.New("config: user cache size must be positive")
+	case c.Repository.UserCacheTTL.Duration < 0:
+		return errors.New("config: user cache ttl must not be negative")
+	case c.Repository.ForumCacheSize <= 0 || c.Repository.ThreadCacheSize <= 0:
+		return errors.New("config: forum and thread cache sizes must be positive")
+	case c.Repository.IdentityCacheTTL.Duration < 0:
+		return errors.New("config: identity cache ttl must not be negative")
+	case c.Repository.ReplicaMaxLag.Duration < 0 || c.Repository.ReadStickiness.Duration < 0:
+		return errors.New("config: replica max lag and read stickiness must not be negative")
+	case c.Repository.ReplicaLagCheckPeriod.Duration <= 0:
+		return errors.New("config: replica lag check period must be positive")
+	case c.Repository.MaxExports <= 0 || c.Repository.MaxExports >= c.DB.MaxConns:
+		return errors.New("config: max exports must be positive and less than db max conns")
+	}
+	return nil
+}
+
+var dsnPassword = regexp.MustCompile(`password=\S+`)
+
+// Redacted returns a copy that is safe to expose, with the database password masked.
+func (c Config) Redacted() Config {
+	c.DB.DSN = redactDSN(c.DB.DSN)
+	replicas := make(StringList, 0, len(c.DB.ReplicaDSNs))
+	for _, dsn := range c.DB.ReplicaDSNs {
+		replicas = append(replicas, redactDSN(dsn))
+	}
+	c.DB.ReplicaDSNs = replicas
+	return c
+}
+
+func redactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+			dsn = u.String()
+		}
+	}
+	return dsnPassword.ReplaceAllString(dsn, "password=xxxxx")
+}
+
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// StringList is a JSON array of strings that is set from a comma-separated flag or environment value.
+type StringList []string
+
+func (l *StringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *StringList) Set(value string) error {
+	*l = nil
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
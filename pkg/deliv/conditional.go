@@ -0,0 +1,96 @@
+package deliv
+
+import (
+	"bytes"
+	"encoding/hex"
+	"github.com/valyala/bytebufferpool"
+	"github.com/valyala/fasthttp"
+	"hash"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"time"
+)
+
+// streamThreshold is the array body size above which OkConditionalArray streams instead of buffering.
+const streamThreshold = 64 * 1024
+
+// OkConditional sends body with a strong ETag of its content, or 304 when the client already holds it.
+// A zero lastModified omits Last-Modified, for responses whose changes a timestamp can not capture.
+func OkConditional(c *fasthttp.RequestCtx, body interface{}, lastModified time.Time) {
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+	if err := marshal(buf, body); err != nil {
+		internalError(c, err)
+		return
+	}
+	h := fnv.New128a()
+	h.Write(buf.B)
+	if !setValidators(c, h, lastModified) {
+		return
+	}
+	c.Write(buf.B)
+}
+
+// OkConditionalArray is OkConditional for listings that may be too large to buffer.
+// The ETag comes from a first encoding pass into the hash; large bodies are encoded again while being sent.
+func OkConditionalArray(c *fasthttp.RequestCtx, array Array, lastModified time.Time) {
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+	h := fnv.New128a()
+	prefix := &prefixWriter{buf: buf, limit: streamThreshold}
+	if _, err := io.Copy(io.MultiWriter(h, prefix), newArrayReader(array)); err != nil {
+		internalError(c, err)
+		return
+	}
+	if !setValidators(c, h, lastModified) {
+		return
+	}
+	if prefix.complete() {
+		c.Write(buf.B)
+		return
+	}
+	c.SetBodyStream(newArrayReader(array), prefix.size)
+}
+
+// setValidators sets ETag and Last-Modified, then answers 304 or prepares a 200 and reports whether to send the body.
+func setValidators(c *fasthttp.RequestCtx, h hash.Hash, lastModified time.Time) bool {
+	etag := `"` + hex.EncodeToString(h.Sum(nil)) + `"`
+	c.Response.Header.Set("ETag", etag)
+	if !lastModified.IsZero() {
+		c.Response.Header.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if notModified(c, etag, lastModified) {
+		c.SetStatusCode(http.StatusNotModified)
+		return false
+	}
+	c.SetStatusCode(http.StatusOK)
+	c.SetContentType("application/json")
+	return true
+}
+
+func notModified(c *fasthttp.RequestCtx, etag string, lastModified time.Time) bool {
+	if ifNoneMatch := c.Request.Header.Peek("If-None-Match"); len(ifNoneMatch) > 0 {
+		return etagMatches(ifNoneMatch, etag)
+	}
+	if lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(string(c.Request.Header.Peek("If-Modified-Since")))
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// etagMatches uses the weak comparison If-None-Match requires.
+func etagMatches(header []byte, etag string) bool {
+	for _, candidate := range bytes.Split(header, []byte(",")) {
+		candidate = bytes.TrimSpace(candidate)
+		candidate = bytes.TrimPrefix(candidate, []byte("W/"))
+		if string(candidate) == "*" || string(candidate) == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -1,9 +1,9 @@
 package deliv
 
 import (
-	"encoding/json"
 	"errors"
 	"github.com/kzon/technopark-sem2-db/pkg/consts"
+	"github.com/valyala/bytebufferpool"
 	"github.com/valyala/fasthttp"
 	"net/http"
 )
@@ -24,6 +24,10 @@ func Created(c *fasthttp.RequestCtx, body interface{}) {
 	sendJSON(c, http.StatusCreated, body)
 }
 
+func NoContent(c *fasthttp.RequestCtx) {
+	c.SetStatusCode(http.StatusNoContent)
+}
+
 func BadRequest(c *fasthttp.RequestCtx, err error) {
 	sendMessage(c, http.StatusBadRequest, err)
 }
@@ -37,6 +41,14 @@ func Error(c *fasthttp.RequestCtx, err error) {
 		ConflictWithMessage(c, err)
 		return
 	}
+	if errors.Is(err, consts.ErrBadRequest) {
+		BadRequest(c, err)
+		return
+	}
+	if errors.Is(err, consts.ErrBusy) {
+		sendMessage(c, http.StatusServiceUnavailable, err)
+		return
+	}
 	if err != nil {
 		internalError(c, err)
 		return
@@ -61,14 +73,17 @@ func ConflictWithMessage(c *fasthttp.RequestCtx, err error) {
 }
 
 func sendJSON(c *fasthttp.RequestCtx, status int, body interface{}) {
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+	if err := marshal(buf, body); err != nil {
+		status, buf.B = http.StatusInternalServerError, buf.B[:0]
+		marshal(buf, message{Message: err.Error()})
+	}
 	c.SetStatusCode(status)
 	c.SetContentType("application/json")
-	response, _ := json.Marshal(body)
-	c.Write(response)
+	c.Write(buf.B)
 }
 
 func sendMessage(c *fasthttp.RequestCtx, status int, err error) {
-	sendJSON(c, status, map[string]string{
-		"message": err.Error(),
-	})
+	sendJSON(c, status, message{Message: err.Error()})
 }
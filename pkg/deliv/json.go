@@ -0,0 +1,122 @@
+package deliv
+
+import (
+	"encoding/json"
+	"github.com/mailru/easyjson"
+	"github.com/mailru/easyjson/jwriter"
+	"github.com/valyala/bytebufferpool"
+	"io"
+	"reflect"
+)
+
+// marshal appends the JSON encoding of body to buf, using generated code when the type has it.
+func marshal(buf *bytebufferpool.ByteBuffer, body interface{}) error {
+	if m, ok := body.(easyjson.Marshaler); ok {
+		if v := reflect.ValueOf(body); v.Kind() == reflect.Ptr && v.IsNil() {
+			_, err := buf.WriteString("null")
+			return err
+		}
+		_, err := easyjson.MarshalToWriter(m, buf)
+		return err
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	_, err = buf.Write(data)
+	return err
+}
+
+// Array is a JSON array that can be encoded one element at a time.
+type Array interface {
+	Len() int
+	MarshalElement(i int, w *jwriter.Writer)
+}
+
+// arrayReader encodes an Array lazily, holding a single element in memory.
+type arrayReader struct {
+	array  Array
+	index  int
+	chunk  []byte
+	offset int
+}
+
+func newArrayReader(array Array) *arrayReader {
+	return &arrayReader{array: array}
+}
+
+// next encodes the following element with its delimiters, returning false once the closing bracket was produced.
+func (r *arrayReader) next() (bool, error) {
+	n := r.array.Len()
+	if r.index > n {
+		return false, nil
+	}
+	w := jwriter.Writer{}
+	if r.index == 0 {
+		w.RawByte('[')
+	} else if r.index < n {
+		w.RawByte(',')
+	}
+	if r.index < n {
+		r.array.MarshalElement(r.index, &w)
+	} else {
+		w.RawByte(']')
+	}
+	r.index++
+	var err error
+	r.chunk, err = w.BuildBytes(r.chunk[:0])
+	r.offset = 0
+	return true, err
+}
+
+func (r *arrayReader) Read(p []byte) (int, error) {
+	for r.offset == len(r.chunk) {
+		ok, err := r.next()
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, r.chunk[r.offset:])
+	r.offset += n
+	return n, nil
+}
+
+func (r *arrayReader) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	for {
+		if r.offset < len(r.chunk) {
+			n, err := w.Write(r.chunk[r.offset:])
+			written += int64(n)
+			r.offset += n
+			if err != nil {
+				return written, err
+			}
+		}
+		ok, err := r.next()
+		if err != nil || !ok {
+			return written, err
+		}
+	}
+}
+
+// prefixWriter keeps written data up to limit bytes and only counts the rest.
+type prefixWriter struct {
+	buf   *bytebufferpool.ByteBuffer
+	limit int
+	size  int
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.size += len(p)
+	if w.size <= w.limit {
+		w.buf.Write(p)
+	}
+	return len(p), nil
+}
+
+func (w *prefixWriter) complete() bool {
+	return w.size <= w.limit
+}
@@ -0,0 +1,8 @@
+//go:generate easyjson message.go
+
+package deliv
+
+//easyjson:json
+type message struct {
+	Message string `json:"message"`
+}
@@ -0,0 +1,102 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type Hook func(ctx context.Context) error
+
+type namedHook struct {
+	name string
+	hook Hook
+}
+
+// Manager stops registered components in reverse registration order once a signal or a fatal error arrives.
+type Manager struct {
+	timeout time.Duration
+	hooks   []namedHook
+	mutex   sync.Mutex
+	failed  chan error
+}
+
+func New(timeout time.Duration) *Manager {
+	return &Manager{
+		timeout: timeout,
+		failed:  make(chan error, 1),
+	}
+}
+
+func (m *Manager) OnStop(name string, hook Hook) {
+	m.mutex.Lock()
+	m.hooks = append(m.hooks, namedHook{name: name, hook: hook})
+	m.mutex.Unlock()
+}
+
+// Fail requests a shutdown because a component can not continue.
+func (m *Manager) Fail(err error) {
+	select {
+	case m.failed <- err:
+	default:
+	}
+}
+
+// Run blocks until SIGINT, SIGTERM or Fail, then runs the stop hooks and returns the first error.
+func (m *Manager) Run() error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	var cause error
+	select {
+	case s := <-signals:
+		log.Printf("received %s, shutting down", s)
+	case cause = <-m.failed:
+		log.Printf("shutting down: %s", cause)
+	}
+	if err := m.Shutdown(); err != nil && cause == nil {
+		cause = err
+	}
+	return cause
+}
+
+// Shutdown gives every hook its own timeout, so a hook that runs out of time does not leave
+// the ones releasing resources after it with an expired context.
+func (m *Manager) Shutdown() error {
+	m.mutex.Lock()
+	hooks := m.hooks
+	m.hooks = nil
+	m.mutex.Unlock()
+
+	var firstErr error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := m.stop(hooks[i]); err != nil {
+			log.Printf("stop %s: %s", hooks[i].name, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+func (m *Manager) stop(h namedHook) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- h.hook(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("%s: %w", h.name, ctx.Err())
+	}
+}
@@ -0,0 +1,274 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Collector writes its samples in the prometheus text exposition format.
+type Collector interface {
+	Collect(w *bufio.Writer)
+}
+
+type Registry struct {
+	mutex      sync.RWMutex
+	collectors []Collector
+}
+
+var Default = &Registry{}
+
+var DefaultBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5}
+
+func Register(collectors ...Collector) {
+	Default.Register(collectors...)
+}
+
+func (r *Registry) Register(collectors ...Collector) {
+	r.mutex.Lock()
+	r.collectors = append(r.collectors, collectors...)
+	r.mutex.Unlock()
+}
+
+func (r *Registry) Write(w io.Writer) error {
+	r.mutex.RLock()
+	collectors := r.collectors
+	r.mutex.RUnlock()
+	buf := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.Collect(buf)
+	}
+	return buf.Flush()
+}
+
+type desc struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+}
+
+func (d *desc) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, escapeHelp(d.help), d.name, d.kind)
+}
+
+func (d *desc) writeLabels(w *bufio.Writer, values []string, extraName, extraValue string) {
+	if len(values) == 0 && extraName == "" {
+		return
+	}
+	w.WriteByte('{')
+	for i, name := range d.labels {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		fmt.Fprintf(w, `%s="%s"`, name, escapeLabel(values[i]))
+	}
+	if extraName != "" {
+		if len(values) > 0 {
+			w.WriteByte(',')
+		}
+		fmt.Fprintf(w, `%s="%s"`, extraName, extraValue)
+	}
+	w.WriteByte('}')
+}
+
+type series struct {
+	mutex  sync.RWMutex
+	values map[string][]string
+	items  map[string]interface{}
+}
+
+func (s *series) get(labelValues []string, create func() interface{}) interface{} {
+	key := strings.Join(labelValues, "\xff")
+	s.mutex.RLock()
+	item, ok := s.items[key]
+	s.mutex.RUnlock()
+	if ok {
+		return item
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if item, ok := s.items[key]; ok {
+		return item
+	}
+	if s.items == nil {
+		s.items = make(map[string]interface{})
+		s.values = make(map[string][]string)
+	}
+	item = create()
+	s.items[key] = item
+	s.values[key] = append([]string(nil), labelValues...)
+	return item
+}
+
+func (s *series) each(f func(labelValues []string, item interface{})) {
+	s.mutex.RLock()
+	keys := make([]string, 0, len(s.items))
+	for key := range s.items {
+		keys = append(keys, key)
+	}
+	s.mutex.RUnlock()
+	sort.Strings(keys)
+	for _, key := range keys {
+		s.mutex.RLock()
+		values, item := s.values[key], s.items[key]
+		s.mutex.RUnlock()
+		f(values, item)
+	}
+}
+
+type CounterVec struct {
+	desc
+	series series
+}
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{desc: desc{name: name, help: help, kind: "counter", labels: labels}}
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) Add(n uint64, labelValues ...string) {
+	counter := c.series.get(labelValues, func() interface{} { return new(uint64) }).(*uint64)
+	atomic.AddUint64(counter, n)
+}
+
+func (c *CounterVec) Collect(w *bufio.Writer) {
+	c.writeHeader(w)
+	c.series.each(func(labelValues []string, item interface{}) {
+		w.WriteString(c.name)
+		c.writeLabels(w, labelValues, "", "")
+		fmt.Fprintf(w, " %d\n", atomic.LoadUint64(item.(*uint64)))
+	})
+}
+
+type histogram struct {
+	buckets []uint64
+	count   uint64
+	sumBits uint64
+}
+
+type HistogramVec struct {
+	desc
+	bounds []float64
+	series series
+}
+
+func NewHistogramVec(name, help string, bounds []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{desc: desc{name: name, help: help, kind: "histogram", labels: labels}, bounds: bounds}
+}
+
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	hist := h.series.get(labelValues, func() interface{} {
+		return &histogram{buckets: make([]uint64, len(h.bounds))}
+	}).(*histogram)
+	if i := sort.SearchFloat64s(h.bounds, v); i < len(h.bounds) {
+		atomic.AddUint64(&hist.buckets[i], 1)
+	}
+	atomic.AddUint64(&hist.count, 1)
+	for {
+		old := atomic.LoadUint64(&hist.sumBits)
+		sum := math.Float64bits(math.Float64frombits(old) + v)
+		if atomic.CompareAndSwapUint64(&hist.sumBits, old, sum) {
+			return
+		}
+	}
+}
+
+func (h *HistogramVec) Collect(w *bufio.Writer) {
+	h.writeHeader(w)
+	h.series.each(func(labelValues []string, item interface{}) {
+		hist := item.(*histogram)
+		var cumulative uint64
+		for i, bound := range h.bounds {
+			cumulative += atomic.LoadUint64(&hist.buckets[i])
+			w.WriteString(h.name + "_bucket")
+			h.writeLabels(w, labelValues, "le", formatFloat(bound))
+			fmt.Fprintf(w, " %d\n", cumulative)
+		}
+		count := atomic.LoadUint64(&hist.count)
+		w.WriteString(h.name + "_bucket")
+		h.writeLabels(w, labelValues, "le", "+Inf")
+		fmt.Fprintf(w, " %d\n", count)
+		w.WriteString(h.name + "_sum")
+		h.writeLabels(w, labelValues, "", "")
+		fmt.Fprintf(w, " %s\n", formatFloat(math.Float64frombits(atomic.LoadUint64(&hist.sumBits))))
+		w.WriteString(h.name + "_count")
+		h.writeLabels(w, labelValues, "", "")
+		fmt.Fprintf(w, " %d\n", count)
+	})
+}
+
+// Func reports a value read at scrape time, such as a pool size or a counter kept elsewhere.
+type Func struct {
+	desc
+	value func() float64
+}
+
+func NewGaugeFunc(name, help string, value func() float64) *Func {
+	return &Func{desc: desc{name: name, help: help, kind: "gauge"}, value: value}
+}
+
+func NewCounterFunc(name, help string, value func() float64) *Func {
+	return &Func{desc: desc{name: name, help: help, kind: "counter"}, value: value}
+}
+
+func (f *Func) Collect(w *bufio.Writer) {
+	f.writeHeader(w)
+	fmt.Fprintf(w, "%s %s\n", f.name, formatFloat(f.value()))
+}
+
+// VecFunc reports one sample per label value, read at scrape time.
+type VecFunc struct {
+	desc
+	values func() map[string]float64
+}
+
+func NewCounterVecFunc(name, help, label string, values func() map[string]float64) *VecFunc {
+	return &VecFunc{desc: desc{name: name, help: help, kind: "counter", labels: []string{label}}, values: values}
+}
+
+func NewGaugeVecFunc(name, help, label string, values func() map[string]float64) *VecFunc {
+	return &VecFunc{desc: desc{name: name, help: help, kind: "gauge", labels: []string{label}}, values: values}
+}
+
+func (f *VecFunc) Collect(w *bufio.Writer) {
+	f.writeHeader(w)
+	values := f.values()
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		w.WriteString(f.name)
+		f.writeLabels(w, []string{key}, "", "")
+		fmt.Fprintf(w, " %s\n", formatFloat(values[key]))
+	}
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
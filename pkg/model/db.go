@@ -1,5 +1,10 @@
+//go:generate easyjson db.go
+
 package model
 
+import "time"
+
+//easyjson:json
 type (
 	User struct {
 		ID       int    `db:"id" json:"-"`
@@ -16,6 +21,8 @@ type (
 		Slug    string `db:"slug" json:"slug"`
 		Posts   int    `db:"posts" json:"posts"`
 		Threads int    `db:"threads" json:"threads"`
+
+		Modified time.Time `db:"modified" json:"-"`
 	}
 
 	Thread struct {
@@ -27,18 +34,22 @@ type (
 		Votes   int    `db:"votes" json:"votes"`
 		Slug    string `db:"slug" json:"slug"`
 		Created string `db:"created" json:"created"`
+
+		Modified time.Time `db:"modified" json:"-"`
 	}
 
 	Post struct {
 		ID       int    `db:"id" json:"id"`
 		Parent   int    `db:"parent" json:"parent"`
-		Path     string `db:"path" json:"-"`
+		Path     Path   `db:"path" json:"-"`
 		Author   string `db:"author" json:"author"`
 		Forum    string `db:"forum" json:"forum"`
 		Thread   int    `db:"thread" json:"thread"`
 		Message  string `db:"message" json:"message"`
 		IsEdited bool   `db:"isEdited" json:"isEdited"`
 		Created  string `db:"created" json:"created"`
+
+		Modified time.Time `db:"modified" json:"-"`
 	}
 
 	Vote struct {
@@ -48,7 +59,7 @@ type (
 		Voice    int    `db:"voice" json:"voice"`
 	}
 
-	Users   = []*User
-	Threads = []*Thread
-	Posts   = []*Post
+	Users   []*User
+	Threads []*Thread
+	Posts   []*Post
 )
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Path is a post materialized path stored as a postgres int[]: ids from the root down to the post.
+type Path []int
+
+func (p Path) Root() int {
+	if len(p) == 0 {
+		return 0
+	}
+	return p[0]
+}
+
+func (p Path) Child(id int) Path {
+	child := make(Path, len(p), len(p)+1)
+	copy(child, p)
+	return append(child, id)
+}
+
+func (p Path) Value() (driver.Value, error) {
+	return p.String(), nil
+}
+
+func (p Path) String() string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, id := range p {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(id))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func (p *Path) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	case nil:
+		*p = nil
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into path", src)
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}")
+	if s == "" {
+		*p = Path{}
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	path := make(Path, len(parts))
+	for i, part := range parts {
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			return fmt.Errorf("invalid path %q: %w", s, err)
+		}
+		path[i] = id
+	}
+	*p = path
+	return nil
+}
@@ -0,0 +1,12 @@
+package model
+
+import "github.com/mailru/easyjson/jwriter"
+
+func (p Posts) Len() int {
+	return len(p)
+}
+
+// MarshalElement lets long post listings be streamed one post at a time.
+func (p Posts) MarshalElement(i int, w *jwriter.Writer) {
+	p[i].MarshalEasyJSON(w)
+}
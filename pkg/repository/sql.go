@@ -1,14 +1,22 @@
 package repository
 
 import (
-	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
 	"github.com/kzon/technopark-sem2-db/pkg/consts"
 )
 
+const uniqueViolation = "23505"
+
 func Error(err error) error {
-	switch err {
-	case sql.ErrNoRows:
+	var pgErr *pgconn.PgError
+	switch {
+	case err == pgx.ErrNoRows:
 		return consts.ErrNotFound
+	case errors.As(err, &pgErr) && pgErr.Code == uniqueViolation:
+		return fmt.Errorf("%w: %s", consts.ErrConflict, pgErr.Message)
 	default:
 		return err
 	}